@@ -0,0 +1,41 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// LastAppliedConfigAnnotation is the annotation used by Kubernetes to store the last applied configuration
+const LastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Apply creates a three-way JSONPatch which applies the desired JSON to the live JSON like `kubectl apply`. The original
+// JSON is decoded from the last applied annotation (an empty annotation means that nothing was applied before). Besides
+// the patch, it returns the new value of the last applied annotation which is the encoded desired JSON.
+func Apply(desired, live interface{}, lastAppliedAnnotation string, options ...Option) (JSONPatchList, string, error) {
+	if desired == nil {
+		return JSONPatchList{}, "", errors.New("desired JSON must not be nil")
+	}
+
+	var original interface{}
+	if lastAppliedAnnotation != "" {
+		value := reflect.New(reflect.TypeOf(desired))
+		if err := json.Unmarshal([]byte(lastAppliedAnnotation), value.Interface()); err != nil {
+			return JSONPatchList{}, "", fmt.Errorf("failed to decode last applied annotation: %w", err)
+		}
+		original = value.Elem().Interface()
+	}
+
+	list, err := CreateThreeWayJSONPatch(desired, live, original, options...)
+	if err != nil {
+		return JSONPatchList{}, "", err
+	}
+
+	annotation, err := json.Marshal(desired)
+	if err != nil {
+		return JSONPatchList{}, "", fmt.Errorf("failed to encode last applied annotation: %w", err)
+	}
+
+	return list, string(annotation), nil
+}
@@ -0,0 +1,47 @@
+package jsonpatch_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+var _ = Describe("Apply", func() {
+	Context("Apply", func() {
+		It("should apply the desired JSON without last applied annotation", func() {
+			list, annotation, err := jsonpatch.Apply(C{Str: "desired", StrMap: map[string]string{"a": "1"}}, C{StrMap: map[string]string{"b": "2"}}, "")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.String()).Should(MatchJSON(`[{"op":"add","path":"/str","value":"desired"},{"op":"add","path":"/strmap/a","value":"1"}]`))
+			Ω(annotation).Should(MatchJSON(`{"str":"desired","strmap":{"a":"1"},"intmap":null,"boolmap":null,"structmap":null,"ptrmap":null}`))
+		})
+		It("should only remove values which were applied before", func() {
+			_, annotation, err := jsonpatch.Apply(C{StrMap: map[string]string{"a": "1", "b": "2"}}, C{}, "")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			list, annotation, err := jsonpatch.Apply(C{StrMap: map[string]string{"a": "1"}}, C{StrMap: map[string]string{"a": "1", "b": "2", "c": "3"}}, annotation)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.String()).Should(MatchJSON(`[{"op":"remove","path":"/strmap/b"}]`))
+			Ω(annotation).Should(MatchJSON(`{"strmap":{"a":"1"},"intmap":null,"boolmap":null,"structmap":null,"ptrmap":null}`))
+		})
+		It("should apply pointers", func() {
+			_, annotation, err := jsonpatch.Apply(&B{Str: "a", Int: 1}, &B{}, "")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			list, _, err := jsonpatch.Apply(&B{Int: 2}, &B{Str: "a", Int: 1}, annotation)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.String()).Should(MatchJSON(`[{"op":"remove","path":"/str"},{"op":"replace","path":"/int","value":2}]`))
+		})
+		It("should fail for an invalid last applied annotation", func() {
+			_, _, err := jsonpatch.Apply(C{}, C{}, "{")
+			Ω(err).Should(HaveOccurred())
+		})
+		It("should fail for a nil desired JSON", func() {
+			_, _, err := jsonpatch.Apply(nil, C{}, `{"str":"a"}`)
+			Ω(err).Should(HaveOccurred())
+
+			_, _, err = jsonpatch.Apply(nil, C{}, "")
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
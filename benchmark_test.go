@@ -0,0 +1,68 @@
+package jsonpatch_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+// benchmarkObjects creates a modified and current object with n struct elements in their slices
+func benchmarkObjects(n int) (G, G) {
+	var modified, current G
+	for j := 0; j < n; j++ {
+		str := strconv.Itoa(j)
+		modified.D.StructSlice = append(modified.D.StructSlice, C{Str: str, StrMap: map[string]string{"key": str}})
+		current.D.StructSlice = append(current.D.StructSlice, C{Str: str, StrMap: map[string]string{"key": "old"}})
+		modified.D.PtrSliceWithKey = append(modified.D.PtrSliceWithKey, &B{Str: str, Int: j})
+		current.D.PtrSliceWithKey = append(current.D.PtrSliceWithKey, &B{Str: str})
+	}
+
+	return modified, current
+}
+
+func BenchmarkCreateJSONPatch(b *testing.B) {
+	modified, current := benchmarkObjects(100)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := jsonpatch.CreateJSONPatch(modified, current); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCreateJSONPatch_ignore_slice_order(b *testing.B) {
+	modified, current := benchmarkObjects(100)
+	option := jsonpatch.IgnoreSliceOrderWithPattern([]jsonpatch.IgnorePattern{{Pattern: "/d/ptrWithKey", JSONField: "str"}})
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := jsonpatch.CreateJSONPatch(modified, current, option); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCreateThreeWayJSONPatch(b *testing.B) {
+	modified, current := benchmarkObjects(100)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := jsonpatch.CreateThreeWayJSONPatch(modified, current, current); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEqual(b *testing.B) {
+	modified, _ := benchmarkObjects(100)
+	current, _ := benchmarkObjects(100)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if !jsonpatch.Equal(modified, current) {
+			b.Fatal("not equal")
+		}
+	}
+}
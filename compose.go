@@ -0,0 +1,221 @@
+package jsonpatch
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// index matches the reference tokens of array indices
+var index = regexp.MustCompile(`^(0|[1-9][0-9]*|-)$`)
+
+// Compose composes two patches which are applied one after the other into a single equivalent patch, without applying
+// them to a document. Operations of b are folded into the operations of a: an 'add' or 'replace' followed by a
+// 'replace' of the same value is collapsed into a single operation, an 'add' of an array element followed by a 'remove'
+// cancels out, and values which are changed after they were added (or replaced) are changed directly in the added value.
+// Array indices are shifted across the elements which were added or removed in between. Operations other than 'add',
+// 'replace' and 'remove' are kept in their order.
+// NOTE: since the patches are not applied, reference tokens which are numbers (or '-') are considered to be array
+// indices. An 'add' to an object might replace an existing member, hence an 'add' of a member followed by a 'remove' is
+// kept as it is: the 'remove' alone would fail for a new member and nothing at all would keep an existing one.
+func Compose(a, b JSONPatchList) (JSONPatchList, error) {
+	var (
+		ops []operation
+		err error
+	)
+	for _, patch := range append(a.List(), b.List()...) {
+		if ops, err = fold(ops, parseOperation(patch)); err != nil {
+			return JSONPatchList{}, err
+		}
+	}
+
+	return newJSONPatchListFromOperations(ops)
+}
+
+// parseOperation creates an operation from a JSONPatch without inspecting its target
+func parseOperation(patch JSONPatch) operation {
+	op := operation{
+		patch:   patch,
+		origin:  patch,
+		pointer: ParseJSONPointer(patch.Path),
+	}
+	op.element = len(op.pointer) > 1 && index.MatchString(op.pointer[len(op.pointer)-1])
+	// only an 'add' of an array element is known to insert a new value, an 'add' of an object member might replace it
+	op.insert = patch.Operation == "add" && op.element
+
+	return op
+}
+
+// folding is the result of moving an operation in front of a preceding operation
+type folding int
+
+const (
+	// blocked is used if the operation depends on the preceding operation and can't be moved
+	blocked folding = iota
+	// swapped is used if the operations are independent and their order can be swapped
+	swapped
+	// merged is used if both operations are merged into a single one
+	merged
+	// cancelled is used if the operations cancel each other out
+	cancelled
+	// shadowed is used if the preceding operation is overwritten by the operation
+	shadowed
+)
+
+// fold folds the operation x into a sequence of operations: x is moved in front of the preceding operations as long as
+// they are independent, in order to merge it with an operation on the same value. If x can't be merged, it is appended
+// to the sequence.
+func fold(ops []operation, x operation) ([]operation, error) {
+	folded, appended := append([]operation{}, ops...), x
+	changed := false
+	for j := len(folded) - 1; j >= 0; j-- {
+		result, y, z, err := commute(folded[j], x)
+		if err != nil {
+			return nil, err
+		}
+
+		switch result {
+		case swapped:
+			folded[j], x = y, z
+			continue
+		case shadowed:
+			folded, changed = append(folded[:j], folded[j+1:]...), true
+			continue
+		case merged:
+			folded[j] = z
+			return folded, nil
+		case cancelled:
+			return append(folded[:j], folded[j+1:]...), nil
+		}
+
+		if changed {
+			return append(folded[:j+1], append([]operation{x}, folded[j+1:]...)...), nil
+		}
+		break
+	}
+	if changed {
+		return append([]operation{x}, folded...), nil
+	}
+
+	return append(ops, appended), nil
+}
+
+// commute moves the operation x in front of the preceding operation y. If the operations are swapped, it returns y
+// rebased on x and x rebased on the context before y, if they are merged, it returns the merged operation as z.
+func commute(y, x operation) (folding, operation, operation, error) {
+	// other operations (e.g. 'move' or 'test') are kept in their order
+	if !y.supported() || !x.supported() {
+		return blocked, y, x, nil
+	}
+
+	// y adds or removes an array element and x is applied to an element of the same array
+	if position := len(y.pointer) - 1; y.structural() && len(x.pointer) > position && hasPrefix(x.pointer, y.pointer[:position]) {
+		i, errI := strconv.Atoi(y.pointer[position])
+		j, errJ := strconv.Atoi(x.pointer[position])
+		target := len(x.pointer) == position+1
+		switch {
+		case x.pointer[position] == "-" && target && x.insert && errI == nil:
+			return swapped, y, x, nil
+		case errI != nil || errJ != nil:
+			return blocked, y, x, nil
+		case y.insert && j == i && target && x.insert:
+			return swapped, y.withIndex(position, i+1), x, nil
+		case y.insert && j == i:
+			return absorb(y, x)
+		case y.removal() && j == i && target && x.insert:
+			return merged, y, replaced(x), nil
+		case y.insert && j > i:
+			return swapped, y, x.withIndex(position, j-1), nil
+		case y.removal() && j >= i:
+			return swapped, y, x.withIndex(position, j+1), nil
+		case target && x.insert:
+			return swapped, y.withIndex(position, i+1), x, nil
+		case target && x.removal():
+			return swapped, y.withIndex(position, i-1), x, nil
+		}
+
+		return swapped, y, x, nil
+	}
+
+	// x adds or removes an array element and y is applied to an element of the same array
+	if position := len(x.pointer) - 1; x.structural() && len(y.pointer) > position && hasPrefix(y.pointer, x.pointer[:position]) {
+		k, errK := strconv.Atoi(x.pointer[position])
+		m, errM := strconv.Atoi(y.pointer[position])
+		switch {
+		case x.pointer[position] == "-":
+			return swapped, y, x, nil
+		case errK != nil || errM != nil:
+			return blocked, y, x, nil
+		case x.insert && m >= k:
+			return swapped, y.withIndex(position, m+1), x, nil
+		case x.removal() && m == k:
+			return shadowed, y, x, nil
+		case x.removal() && m > k:
+			return swapped, y.withIndex(position, m-1), x, nil
+		}
+
+		return swapped, y, x, nil
+	}
+
+	switch {
+	case len(x.pointer) == len(y.pointer) && hasPrefix(x.pointer, y.pointer):
+		switch {
+		case y.removal() && x.patch.Operation == "add":
+			return merged, y, replaced(x), nil
+		case y.removal():
+			return blocked, y, x, nil
+		}
+		return absorb(y, x)
+	case hasPrefix(x.pointer, y.pointer):
+		if y.removal() {
+			return blocked, y, x, nil
+		}
+		return absorb(y, x)
+	case hasPrefix(y.pointer, x.pointer):
+		return shadowed, y, x, nil
+	}
+
+	return swapped, y, x, nil
+}
+
+// absorb merges the operation x into the preceding operation y which adds or replaces a value which contains the
+// target of x
+func absorb(y, x operation) (folding, operation, operation, error) {
+	if len(x.pointer) == len(y.pointer) {
+		if x.removal() && y.insert {
+			return cancelled, y, x, nil
+		} else if x.removal() && y.patch.Operation == "add" {
+			// the added member might have existed before
+			return blocked, y, x, nil
+		} else if x.removal() {
+			y.patch = JSONPatch{Operation: "remove", Path: y.patch.Path}
+			y.insert = false
+		} else {
+			y.patch.Value = x.patch.Value
+		}
+
+		return merged, y, y, nil
+	}
+
+	value, err := decode(y.patch.Value)
+	if err != nil {
+		return blocked, y, x, err
+	}
+	operand, err := decode(x.patch.Value)
+	if err != nil {
+		return blocked, y, x, err
+	}
+	if y.patch.Value, err = applyOperation(value, x.pointer[len(y.pointer):], x.patch.Operation, operand, x.patch.Path); err != nil {
+		return blocked, y, x, err
+	}
+
+	return merged, y, y, nil
+}
+
+// replaced converts an operation which adds a value at the position of a removed value into a replacement
+func replaced(x operation) operation {
+	op := x
+	op.patch.Operation = "replace"
+	op.insert = false
+
+	return op
+}
@@ -0,0 +1,168 @@
+package jsonpatch_test
+
+import (
+	"encoding/json"
+	"strconv"
+
+	jsonpatch2 "github.com/evanphx/json-patch/v5"
+	"github.com/go-faker/faker/v4"
+	"github.com/go-faker/faker/v4/pkg/options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+var _ = Describe("Compose", func() {
+	Context("Compose", func() {
+		It("should collapse operations on the same value", func() {
+			testCompose(C{},
+				[]jsonpatch.JSONPatch{{Operation: "add", Path: "/strmap", Value: map[string]string{}}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/strmap", Value: map[string]string{"a": "1"}}},
+				[]jsonpatch.JSONPatch{{Operation: "add", Path: "/strmap", Value: map[string]string{"a": "1"}}},
+			)
+			testCompose(C{Str: "a"},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/str", Value: "b"}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/str", Value: "c"}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/str", Value: "c"}},
+			)
+			testCompose(C{Str: "a"},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/str", Value: "b"}},
+				[]jsonpatch.JSONPatch{{Operation: "remove", Path: "/str"}},
+				[]jsonpatch.JSONPatch{{Operation: "remove", Path: "/str"}},
+			)
+			testCompose(C{Str: "a"},
+				[]jsonpatch.JSONPatch{{Operation: "remove", Path: "/str"}},
+				[]jsonpatch.JSONPatch{{Operation: "add", Path: "/str", Value: "b"}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/str", Value: "b"}},
+			)
+		})
+		It("should keep added and removed members", func() {
+			for _, base := range []C{{StrMap: map[string]string{}}, {StrMap: map[string]string{"a": "0"}}} {
+				testCompose(base,
+					[]jsonpatch.JSONPatch{{Operation: "add", Path: "/strmap/a", Value: "1"}, {Operation: "add", Path: "/strmap/b", Value: "2"}},
+					[]jsonpatch.JSONPatch{{Operation: "remove", Path: "/strmap/a"}},
+					[]jsonpatch.JSONPatch{{Operation: "add", Path: "/strmap/a", Value: "1"}, {Operation: "add", Path: "/strmap/b", Value: "2"}, {Operation: "remove", Path: "/strmap/a"}},
+				)
+			}
+
+			// the walker adds values of members which already exist with a zero value
+			base := G{}
+			a, err := jsonpatch.CreateJSONPatch(G{A: &A{}}, base)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(a.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "add", Path: "/a", Value: A{}}}))
+			b, err := jsonpatch.NewJSONPatchList([]jsonpatch.JSONPatch{{Operation: "remove", Path: "/a"}})
+			Ω(err).ShouldNot(HaveOccurred())
+			composed := testComposeEquivalent(base, a, b)
+			Ω(composed.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "add", Path: "/a", Value: A{}}, {Operation: "remove", Path: "/a"}}))
+		})
+		It("should cancel added and removed elements", func() {
+			testCompose([]string{"a", "b"},
+				[]jsonpatch.JSONPatch{{Operation: "add", Path: "/1", Value: "c"}},
+				[]jsonpatch.JSONPatch{{Operation: "remove", Path: "/1"}},
+				[]jsonpatch.JSONPatch{},
+			)
+		})
+		It("should change values within added values", func() {
+			testCompose(C{},
+				[]jsonpatch.JSONPatch{{Operation: "add", Path: "/structmap", Value: map[string]B{"a": {Str: "a"}}}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/structmap/a/str", Value: "b"}, {Operation: "add", Path: "/structmap/c", Value: B{}}},
+				[]jsonpatch.JSONPatch{{Operation: "add", Path: "/structmap", Value: map[string]B{"a": {Str: "b"}, "c": {}}}},
+			)
+		})
+		It("should drop operations which are overwritten", func() {
+			testCompose([]B{{Str: "a"}, {Str: "b"}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/0/str", Value: "c"}, {Operation: "replace", Path: "/1/int", Value: 1}},
+				[]jsonpatch.JSONPatch{{Operation: "remove", Path: "/0"}},
+				[]jsonpatch.JSONPatch{{Operation: "remove", Path: "/0"}, {Operation: "replace", Path: "/0/int", Value: 1}},
+			)
+			testCompose(C{Str: "a", StrMap: map[string]string{"a": "1"}},
+				[]jsonpatch.JSONPatch{{Operation: "add", Path: "/strmap/b", Value: "2"}, {Operation: "replace", Path: "/str", Value: "b"}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/strmap", Value: map[string]string{}}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/strmap", Value: map[string]string{}}, {Operation: "replace", Path: "/str", Value: "b"}},
+			)
+		})
+		It("should shift array indices", func() {
+			testCompose([]string{"a", "b", "c"},
+				[]jsonpatch.JSONPatch{{Operation: "add", Path: "/0", Value: "x"}, {Operation: "replace", Path: "/3", Value: "C"}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/0", Value: "y"}, {Operation: "remove", Path: "/1"}, {Operation: "replace", Path: "/2", Value: "D"}},
+				[]jsonpatch.JSONPatch{{Operation: "add", Path: "/0", Value: "y"}, {Operation: "replace", Path: "/3", Value: "D"}, {Operation: "remove", Path: "/1"}},
+			)
+			testCompose([]string{"a", "b", "c"},
+				[]jsonpatch.JSONPatch{{Operation: "remove", Path: "/0"}, {Operation: "add", Path: "/2", Value: "d"}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/2", Value: "e"}, {Operation: "add", Path: "/0", Value: "f"}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/0", Value: "f"}, {Operation: "add", Path: "/3", Value: "e"}},
+			)
+		})
+		It("should fail for invalid patches", func() {
+			a, err := jsonpatch.NewJSONPatchList([]jsonpatch.JSONPatch{{Operation: "add", Path: "/strmap", Value: map[string]string{}}})
+			Ω(err).ShouldNot(HaveOccurred())
+			b, err := jsonpatch.NewJSONPatchList([]jsonpatch.JSONPatch{{Operation: "replace", Path: "/strmap/a/b", Value: "1"}})
+			Ω(err).ShouldNot(HaveOccurred())
+			_, err = jsonpatch.Compose(a, b)
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+	Context("Compose_fuzzy", func() {
+		for i := 0; i < 100; i++ {
+			It("fuzzy "+strconv.Itoa(i), func() {
+				var documents [3]G
+				for j := range documents {
+					Ω(faker.FakeData(&documents[j], options.WithRandomMapAndSliceMaxSize(5))).Should(Succeed())
+				}
+
+				// ignoring the slice order produces operations on arbitrary array indices
+				ignored := jsonpatch.IgnoreSliceOrderWithPattern([]jsonpatch.IgnorePattern{{Pattern: "/d/strs"}, {Pattern: "/d/structs", JSONField: "str"}})
+				a, err := jsonpatch.CreateJSONPatch(documents[1], documents[0], ignored)
+				Ω(err).ShouldNot(HaveOccurred())
+				b, err := jsonpatch.CreateJSONPatch(documents[2], documents[1], ignored)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				composed := testComposeEquivalent(documents[0], a, b)
+				Ω(composed.Len()).Should(BeNumerically("<=", a.Len()+b.Len()))
+			})
+		}
+	})
+})
+
+func testCompose(base interface{}, a, b, expected []jsonpatch.JSONPatch) {
+	listA, err := jsonpatch.NewJSONPatchList(a)
+	Ω(err).ShouldNot(HaveOccurred())
+	listB, err := jsonpatch.NewJSONPatchList(b)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	composed := testComposeEquivalent(base, listA, listB)
+	if len(expected) == 0 {
+		Ω(composed.Empty()).Should(BeTrue())
+	} else {
+		expectedJSON, err := json.Marshal(expected)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(composed.Raw()).Should(MatchJSON(expectedJSON))
+	}
+}
+
+func testComposeEquivalent(base interface{}, a, b jsonpatch.JSONPatchList) jsonpatch.JSONPatchList {
+	composed, err := jsonpatch.Compose(a, b)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// the composed patch must be equivalent to both patches
+	baseJSON, err := json.Marshal(base)
+	Ω(err).ShouldNot(HaveOccurred())
+	expectedJSON := testApplyPatches(baseJSON, a, b)
+	Ω(testApplyPatches(baseJSON, composed)).Should(MatchJSON(expectedJSON))
+
+	return composed
+}
+
+func testApplyPatches(document []byte, lists ...jsonpatch.JSONPatchList) []byte {
+	for _, list := range lists {
+		if !list.Empty() {
+			jsonPatch, err := jsonpatch2.DecodePatch(list.Raw())
+			Ω(err).ShouldNot(HaveOccurred())
+			document, err = jsonPatch.Apply(document)
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+	}
+
+	return document
+}
@@ -0,0 +1,88 @@
+package jsonpatch
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// concurrencyThreshold is the min number of children of a map or slice which are walked concurrently
+const concurrencyThreshold = 64
+
+// concurrent reports whether the n children of a map or slice are walked concurrently
+func (w *walker) concurrent(n int) bool {
+	return w.concurrency > 1 && n >= concurrencyThreshold
+}
+
+// fork creates a walker with the same configuration which walks a part of the children of a map or slice in its own
+// goroutine. The options are applied again, hence the fork records the errors of its predicates, handlers and deciders
+// itself. Its patches and errors are merged by the walker afterward. The j-th fork stops as soon as one of the forks before
+// it fails, the index of the first failed fork is stored in halted.
+func (w *walker) fork(j int, halted *atomic.Int64) *walker {
+	f := newWalkerContext(w.ctx, w.options...)
+	f.ignoredSlices = slices.Clone(w.ignoredSlices)
+	f.threeWay = w.threeWay
+	f.atomic = slices.Clone(w.atomic)
+	f.parents = slices.Clone(w.parents)
+	f.modifiedRoot, f.currentRoot = w.modifiedRoot, w.currentRoot
+	f.concurrency = 0
+	f.parent, f.index, f.halted = w, j, halted
+
+	return f
+}
+
+// walkConcurrently walks the n children of a map or slice with at most w.concurrency forked walkers in parallel, each
+// of them walks a contiguous range of the children. Their patches, conflicts and changed immutable values are merged in
+// the order of the children, as are their errors. Unless all errors are collected, the first error in that order aborts
+// the walk.
+func (w *walker) walkConcurrently(n int, walkChild func(w *walker, j int) error) error {
+	size := (n + w.concurrency - 1) / w.concurrency
+	forks := make([]*walker, (n+size-1)/size)
+	errs := make([]error, len(forks))
+	halted := &atomic.Int64{}
+	halted.Store(int64(len(forks)))
+
+	var wg sync.WaitGroup
+	for j := range forks {
+		forks[j] = w.fork(j, halted)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := j * size; k < min((j+1)*size, n); k++ {
+				if errs[j] = walkChild(forks[j], k); errs[j] != nil {
+					forks[j].halt()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for j, fork := range forks {
+		w.mu.Lock()
+		w.errs = append(w.errs, fork.errs...)
+		w.mu.Unlock()
+		if errs[j] != nil {
+			return errs[j]
+		}
+		if w.aborted() {
+			// the later forks were stopped by the errors of this one
+			return nil
+		}
+		w.patchList = append(w.patchList, fork.patchList...)
+		w.conflicts = append(w.conflicts, fork.conflicts...)
+		w.violations = append(w.violations, fork.violations...)
+	}
+	w.flush()
+
+	return nil
+}
+
+// halt stops the forks after this one
+func (w *walker) halt() {
+	for halted := w.halted.Load(); int64(w.index) < halted; halted = w.halted.Load() {
+		if w.halted.CompareAndSwap(halted, int64(w.index)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package jsonpatch_test
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+var _ = Describe("Concurrency", func() {
+	var (
+		modified G
+		current  G
+	)
+	BeforeEach(func() {
+		modified = G{C: C{StrMap: map[string]string{}, StructMap: map[string]B{}}}
+		current = G{C: C{StrMap: map[string]string{}, StructMap: map[string]B{}}}
+		for j := 0; j < 200; j++ {
+			key := strconv.Itoa(j)
+			modified.C.StrMap[key] = key
+			if j%3 != 0 {
+				current.C.StrMap[key] = "old"
+			}
+			modified.C.StructMap[key] = B{Str: key, Int: j}
+			current.C.StructMap[key] = B{Str: key}
+			modified.D.StructSlice = append(modified.D.StructSlice, C{Str: key, StrMap: map[string]string{"key": key}})
+			current.D.StructSlice = append(current.D.StructSlice, C{Str: key, StrMap: map[string]string{"key": "old"}})
+		}
+		modified.D.StructSlice = append(modified.D.StructSlice, C{Str: "new"})
+		for j := 0; j < 100; j++ {
+			current.C.StrMap["removed"+strconv.Itoa(j)] = "removed"
+		}
+	})
+
+	Context("WithConcurrency", func() {
+		It("should create the same patch as the sequential walk", func() {
+			list, err := jsonpatch.CreateJSONPatch(modified, current)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			for _, n := range []int{2, 3, 8} {
+				concurrentList, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithConcurrency(n))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(concurrentList.List()).Should(ConsistOf(list.List()))
+				testPatchWithExpected(modified, current, modified, jsonpatch.WithConcurrency(n))
+			}
+		})
+		It("should merge the patches in a deterministic order", func() {
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithConcurrency(4))
+			Ω(err).ShouldNot(HaveOccurred())
+			for j := 0; j < 10; j++ {
+				other, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithConcurrency(4))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(other.List()).Should(Equal(list.List()))
+			}
+		})
+		It("should create three-way patches", func() {
+			list, err := jsonpatch.CreateThreeWayJSONPatch(modified, current, current)
+			Ω(err).ShouldNot(HaveOccurred())
+			concurrentList, err := jsonpatch.CreateThreeWayJSONPatch(modified, current, current, jsonpatch.WithConcurrency(4))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(concurrentList.List()).Should(ConsistOf(list.List()))
+		})
+		It("should stream the patches", func() {
+			var patches []jsonpatch.JSONPatch
+			Ω(jsonpatch.WalkPatch(func(patch jsonpatch.JSONPatch) error {
+				patches = append(patches, patch)
+				return nil
+			}, modified, current, jsonpatch.WithConcurrency(4))).Should(Succeed())
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithConcurrency(4))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(patches).Should(Equal(list.List()))
+		})
+		It("should collect the errors and changed immutable values", func() {
+			errInvalid := errors.New("invalid")
+			_, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithConcurrency(4), jsonpatch.WithCollectErrors(),
+				jsonpatch.Immutable("/c/structmap/*"),
+				jsonpatch.WithErrorPredicate(jsonpatch.ErrorFuncs{
+					ReplaceFunc: func(pointer jsonpatch.JSONPointer, _, _ interface{}) (bool, error) {
+						if pointer.Match("/d/structs/*/str") || pointer.String() == "/d/structs/7/strmap/key" {
+							return false, errInvalid
+						}
+						return true, nil
+					},
+				}))
+			Ω(errors.Is(err, errInvalid)).Should(BeTrue())
+			Ω(err).Should(MatchError(ContainSubstring("invalid at: /d/structs/7/strmap/key")))
+
+			var immutableErr *jsonpatch.ImmutableError
+			Ω(errors.As(err, &immutableErr)).Should(BeTrue())
+			Ω(immutableErr.Pointers).Should(HaveLen(199))
+		})
+		It("should report the errors in the order of the children", func() {
+			predicate := jsonpatch.ErrorFuncs{
+				ReplaceFunc: func(pointer jsonpatch.JSONPointer, _, _ interface{}) (bool, error) {
+					if pointer.Match("/d/structs/*/strmap/key") {
+						return false, errors.New("invalid")
+					}
+					return true, nil
+				},
+			}
+			_, expected := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithCollectErrors(), jsonpatch.WithErrorPredicate(predicate))
+			Ω(expected).Should(HaveOccurred())
+			for j := 0; j < 10; j++ {
+				_, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithConcurrency(4), jsonpatch.WithCollectErrors(), jsonpatch.WithErrorPredicate(predicate))
+				Ω(err).Should(MatchError(expected.Error()))
+
+				_, err = jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithConcurrency(4), jsonpatch.WithErrorPredicate(predicate))
+				Ω(err).Should(MatchError("invalid at: /d/structs/0/strmap/key"))
+			}
+		})
+		It("should stop the other goroutines after an error", func() {
+			failed := make(chan struct{})
+			var calls atomic.Int32
+			_, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithConcurrency(4), jsonpatch.WithErrorPredicate(jsonpatch.ErrorFuncs{
+				ReplaceFunc: func(pointer jsonpatch.JSONPointer, _, _ interface{}) (bool, error) {
+					if !pointer.Match("/d/structs/*/strmap/key") {
+						return true, nil
+					}
+					if pointer.String() == "/d/structs/0/strmap/key" {
+						defer close(failed)
+						return false, errors.New("invalid")
+					}
+					<-failed
+					calls.Add(1)
+					return true, nil
+				},
+			}))
+			Ω(err).Should(MatchError("invalid at: /d/structs/0/strmap/key"))
+			Ω(calls.Load()).Should(BeNumerically("<", 10))
+		})
+		It("should fail", func() {
+			_, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithConcurrency(4), jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(context jsonpatch.DecisionContext) jsonpatch.Decision {
+				if context.Pointer.String() == "/d/structs/150" {
+					return jsonpatch.Fail
+				}
+				return jsonpatch.Include
+			})))
+			Ω(errors.Is(err, jsonpatch.ErrDecisionFailed)).Should(BeTrue())
+		})
+	})
+})
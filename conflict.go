@@ -0,0 +1,46 @@
+package jsonpatch
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrConflict is returned if a three-way patch can't be created due to a conflict
+var ErrConflict = errors.New("conflict")
+
+// Conflict describes a value which was changed in the current JSON as well as in the modified JSON in different ways
+// relative to the original JSON. Values which are not part of a JSON (e.g. removed values) are nil.
+type Conflict struct {
+	Pointer  JSONPointer
+	Original interface{}
+	Current  interface{}
+	Modified interface{}
+}
+
+// ThreeWayResult is the result of a three-way patch creation
+type ThreeWayResult struct {
+	// Patch is the three-way JSONPatch which is applied to the current JSON
+	Patch JSONPatchList
+
+	// Conflicts lists all conflicts which were detected (and resolved) during the patch creation
+	Conflicts []Conflict
+}
+
+// ConflictResolver resolves a Conflict by returning true if the modified value should be patched or false if the
+// current value should be kept. If an error is returned the patch creation is aborted.
+type ConflictResolver func(conflict Conflict) (bool, error)
+
+// ResolveOurs is a ConflictResolver which resolves all conflicts in favor of the modified JSON
+func ResolveOurs(_ Conflict) (bool, error) {
+	return true, nil
+}
+
+// ResolveTheirs is a ConflictResolver which resolves all conflicts in favor of the current JSON
+func ResolveTheirs(_ Conflict) (bool, error) {
+	return false, nil
+}
+
+// ResolveFail is a ConflictResolver which aborts the patch creation on the first conflict with an ErrConflict
+func ResolveFail(conflict Conflict) (bool, error) {
+	return false, fmt.Errorf("%w at: %s", ErrConflict, conflict.Pointer)
+}
@@ -0,0 +1,248 @@
+package jsonpatch_test
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+type contextKey string
+
+// countdownContext is canceled after its error was checked n times, it counts the checks after its cancellation
+type countdownContext struct {
+	context.Context
+	n        int
+	canceled int
+}
+
+func (c *countdownContext) Err() error {
+	if c.n > 0 {
+		c.n--
+		return nil
+	}
+	c.canceled++
+	return context.Canceled
+}
+
+// valuePredicate only patches the paths which are stored in the context
+type valuePredicate struct {
+	jsonpatch.Funcs
+}
+
+func (valuePredicate) WithContext(ctx context.Context) jsonpatch.Predicate {
+	return jsonpatch.OnlyPaths(ctx.Value(contextKey("paths")).(string))
+}
+
+// valueHandler replaces the values by the value which is stored in the context
+type valueHandler struct {
+	jsonpatch.DefaultHandler
+}
+
+func (*valueHandler) WithContext(ctx context.Context) jsonpatch.Handler {
+	return jsonpatch.HandlerFuncs{
+		ReplaceFunc: func(pointer jsonpatch.JSONPointer, _, _ interface{}) []jsonpatch.JSONPatch {
+			return []jsonpatch.JSONPatch{{Operation: "replace", Path: pointer.String(), Value: ctx.Value(contextKey("value"))}}
+		},
+	}
+}
+
+var _ = Describe("Context", func() {
+	var (
+		modified G
+		current  G
+	)
+	BeforeEach(func() {
+		modified = G{C: C{Str: "new", StrMap: map[string]string{}}}
+		current = G{C: C{Str: "old", StrMap: map[string]string{}}}
+		for j := 0; j < 200; j++ {
+			modified.C.StrMap[strconv.Itoa(j)] = strconv.Itoa(j)
+			current.C.StrMap[strconv.Itoa(j)] = "old"
+		}
+	})
+
+	Context("CreateJSONPatchContext", func() {
+		It("should create the same patch as CreateJSONPatch", func() {
+			list, err := jsonpatch.CreateJSONPatch(modified, current)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			contextList, err := jsonpatch.CreateJSONPatchContext(context.Background(), modified, current)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(contextList.List()).Should(ConsistOf(list.List()))
+		})
+		It("should fail if the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := jsonpatch.CreateJSONPatchContext(ctx, modified, current)
+			Ω(err).Should(MatchError(context.Canceled))
+		})
+		It("should fail if the deadline of the context is exceeded", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+			defer cancel()
+
+			_, err := jsonpatch.CreateJSONPatchContext(ctx, modified, current)
+			Ω(err).Should(MatchError(context.DeadlineExceeded))
+		})
+		It("should stop the walk as soon as the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			calls := 0
+			_, err := jsonpatch.CreateJSONPatchContext(ctx, modified, current, jsonpatch.WithPredicate(jsonpatch.Funcs{
+				ReplaceFunc: func(pointer jsonpatch.JSONPointer, _, _ interface{}) bool {
+					if pointer.Match("/c/strmap/*") {
+						calls++
+						cancel()
+					}
+					return true
+				},
+			}))
+			Ω(err).Should(MatchError(context.Canceled))
+			Ω(calls).Should(Equal(1))
+		})
+		It("should stop the concurrent walk if the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			_, err := jsonpatch.CreateJSONPatchContext(ctx, modified, current, jsonpatch.WithConcurrency(4), jsonpatch.WithPredicate(jsonpatch.Funcs{
+				ReplaceFunc: func(pointer jsonpatch.JSONPointer, _, _ interface{}) bool {
+					if pointer.String() == "/c/strmap/100" {
+						cancel()
+					}
+					return true
+				},
+			}))
+			Ω(err).Should(MatchError(context.Canceled))
+		})
+	})
+	Context("leaves", func() {
+		It("should stop adding and removing values as soon as the context is canceled", func() {
+			modified, current := map[string]string{}, map[string]string{"removed": "removed"}
+			for j := 0; j < 1000; j++ {
+				modified[strconv.Itoa(j)] = "new"
+				current[strconv.Itoa(-j)] = "old"
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			calls := 0
+			cancelFirst := func() bool {
+				calls++
+				cancel()
+				return true
+			}
+			_, err := jsonpatch.CreateJSONPatchContext(ctx, modified, current, jsonpatch.WithPredicate(jsonpatch.Funcs{
+				AddFunc: func(jsonpatch.JSONPointer, interface{}) bool {
+					return cancelFirst()
+				},
+				RemoveFunc: func(jsonpatch.JSONPointer, interface{}) bool {
+					return cancelFirst()
+				},
+			}))
+			Ω(err).Should(MatchError(context.Canceled))
+			Ω(calls).Should(Equal(1))
+		})
+		It("should stop aligning the slices as soon as the context is canceled", func() {
+			var modified, current []int
+			for j := 0; j < 200; j++ {
+				modified = append(modified, j)
+				current = append(current, 199-j)
+			}
+
+			ctx := &countdownContext{Context: context.Background(), n: 50}
+			_, err := jsonpatch.CreateThreeWayJSONPatchContext(ctx, modified, current, current)
+			Ω(err).Should(MatchError(context.Canceled))
+			Ω(ctx.canceled).Should(BeNumerically("<", 10))
+		})
+	})
+	Context("WalkPatchContext", func() {
+		It("should emit the same patches as WalkPatch", func() {
+			var patches, contextPatches []jsonpatch.JSONPatch
+			Ω(jsonpatch.WalkPatch(func(patch jsonpatch.JSONPatch) error {
+				patches = append(patches, patch)
+				return nil
+			}, modified, current)).Should(Succeed())
+			Ω(jsonpatch.WalkPatchContext(context.Background(), func(patch jsonpatch.JSONPatch) error {
+				contextPatches = append(contextPatches, patch)
+				return nil
+			}, modified, current)).Should(Succeed())
+			Ω(contextPatches).Should(ConsistOf(patches))
+		})
+		It("should fail if the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			Ω(jsonpatch.WalkPatchContext(ctx, func(jsonpatch.JSONPatch) error {
+				cancel()
+				return nil
+			}, modified, current)).Should(MatchError(context.Canceled))
+		})
+	})
+	Context("WritePatchContext", func() {
+		It("should fail if the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			var buffer bytes.Buffer
+			Ω(jsonpatch.WritePatchContext(ctx, &buffer, modified, current)).Should(MatchError(context.Canceled))
+			Ω(buffer.Len()).Should(BeZero())
+		})
+	})
+	Context("CreateThreeWayJSONPatchContext", func() {
+		It("should create the same patch as CreateThreeWayJSONPatch", func() {
+			list, err := jsonpatch.CreateThreeWayJSONPatch(modified, current, current)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			contextList, err := jsonpatch.CreateThreeWayJSONPatchContext(context.Background(), modified, current, current)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(contextList.List()).Should(ConsistOf(list.List()))
+		})
+		It("should fail if the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := jsonpatch.CreateThreeWayJSONPatchContext(ctx, modified, current, current)
+			Ω(err).Should(MatchError(context.Canceled))
+			_, err = jsonpatch.CreateThreeWayJSONPatchResultContext(ctx, modified, current, current)
+			Ω(err).Should(MatchError(context.Canceled))
+		})
+	})
+	Context("WithPredicate", func() {
+		It("should bind a ContextPredicate to the context", func() {
+			ctx := context.WithValue(context.Background(), contextKey("paths"), "/c/str")
+
+			list, err := jsonpatch.CreateJSONPatchContext(ctx, modified, current, jsonpatch.WithPredicate(valuePredicate{}))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "replace", Path: "/c/str", Value: "new"}}))
+		})
+	})
+	Context("WithHandler", func() {
+		It("should bind a ContextHandler to the context", func() {
+			ctx := context.WithValue(context.Background(), contextKey("value"), "ctx")
+
+			list, err := jsonpatch.CreateJSONPatchContext(ctx, modified, current, jsonpatch.WithPredicate(jsonpatch.OnlyPaths("/c/str")), jsonpatch.WithHandler(&valueHandler{}))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "replace", Path: "/c/str", Value: "ctx"}}))
+		})
+	})
+	Context("WithDecider", func() {
+		It("should pass the context to the decider", func() {
+			ctx := context.WithValue(context.Background(), contextKey("skipped"), "/c/strmap")
+
+			list, err := jsonpatch.CreateJSONPatchContext(ctx, modified, current, jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(context jsonpatch.DecisionContext) jsonpatch.Decision {
+				if context.Pointer.String() == context.Context.Value(contextKey("skipped")) {
+					return jsonpatch.SkipSubtree
+				}
+				return jsonpatch.Include
+			})))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "replace", Path: "/c/str", Value: "new"}}))
+		})
+	})
+})
@@ -0,0 +1,145 @@
+package jsonpatch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// ErrDecisionFailed is returned if a Decider aborts the patch creation with the Fail decision
+var ErrDecisionFailed = errors.New("patch creation failed")
+
+// Decision is the decision of a Decider about a value or a patch
+type Decision int
+
+const (
+	// Include walks into the value or includes the patch (default)
+	Include Decision = iota
+	// Skip drops the patches of the value itself, but still walks into the value
+	Skip
+	// SkipSubtree neither walks into the value nor patches it
+	SkipSubtree
+	// ReplaceWhole patches the value as a whole without walking into it (see WithAtomic)
+	ReplaceWhole
+	// Fail aborts the patch creation with an error
+	Fail
+)
+
+// DecisionContext describes the value or the patch a Decider decides about
+type DecisionContext struct {
+	// Pointer is the path of the value
+	Pointer JSONPointer
+
+	// Operation is the operation of the patch ('add', 'remove' or 'replace') or empty if the walker is about to walk into
+	// a struct, slice or map value
+	Operation string
+
+	// Modified is the value of the modified JSON or nil if it doesn't exist
+	Modified interface{}
+
+	// Current is the value of the current JSON or nil if it doesn't exist
+	Current interface{}
+
+	// ModifiedParent is the parent struct, slice or map value of the modified JSON or nil for the root value
+	ModifiedParent interface{}
+
+	// CurrentParent is the parent struct, slice or map value of the current JSON or nil for the root value
+	CurrentParent interface{}
+
+	// Depth is the depth of the value relative to the prefix
+	Depth int
+
+	// Kind is the kind of the value
+	Kind reflect.Kind
+
+	// ModifiedRoot is the modified JSON
+	ModifiedRoot interface{}
+
+	// CurrentRoot is the current JSON
+	CurrentRoot interface{}
+
+	// Context is the context of the patch creation (see CreateJSONPatchContext)
+	Context context.Context
+}
+
+// Decider decides about every struct, slice and map value before the walker walks into it and about every patch
+type Decider interface {
+	// Decide returns the Decision for the value or patch described by the context
+	Decide(context DecisionContext) Decision
+}
+
+// DeciderFunc is a function that implements Decider
+type DeciderFunc func(context DecisionContext) Decision
+
+// Decide implements Decider
+func (f DeciderFunc) Decide(context DecisionContext) Decision {
+	return f(context)
+}
+
+// ErrorDecider decides like a Decider, but can abort the patch creation with an error. The error is returned by the patch
+// creation together with the pointer.
+type ErrorDecider interface {
+	// Decide returns the Decision for the value or patch described by the context or an error
+	Decide(context DecisionContext) (Decision, error)
+}
+
+// ErrorDeciderFunc is a function that implements ErrorDecider
+type ErrorDeciderFunc func(context DecisionContext) (Decision, error)
+
+// Decide implements ErrorDecider
+func (f ErrorDeciderFunc) Decide(context DecisionContext) (Decision, error) {
+	return f(context)
+}
+
+// errorDecider adapts an ErrorDecider to a Decider which records the errors in the walker
+type errorDecider struct {
+	decider ErrorDecider
+	walker  *walker
+}
+
+// Decide implements Decider
+func (d *errorDecider) Decide(context DecisionContext) Decision {
+	decision, err := d.decider.Decide(context)
+	if err != nil {
+		// the error is recorded instead of ErrDecisionFailed, the value is neither walked nor patched
+		d.walker.fail(context.Pointer, err)
+		return SkipSubtree
+	}
+
+	return decision
+}
+
+// parent is a struct, slice or map value the walker walks into
+type parent struct {
+	length   int
+	modified interface{}
+	current  interface{}
+}
+
+// decisionContext creates the DecisionContext of a value or a patch, the parent is the last one with a shorter pointer
+// because some values are walked into at the same pointer (e.g. the struct values of pointers)
+func (w *walker) decisionContext(pointer JSONPointer, operation string, modified, current interface{}) DecisionContext {
+	context := DecisionContext{
+		Pointer:      pointer,
+		Operation:    operation,
+		Modified:     modified,
+		Current:      current,
+		Depth:        len(pointer) - len(w.prefix),
+		ModifiedRoot: w.modifiedRoot,
+		CurrentRoot:  w.currentRoot,
+		Context:      w.ctx,
+	}
+	if modified != nil {
+		context.Kind = reflect.TypeOf(modified).Kind()
+	} else if current != nil {
+		context.Kind = reflect.TypeOf(current).Kind()
+	}
+	for j := len(w.parents) - 1; j >= 0; j-- {
+		if w.parents[j].length < len(pointer) {
+			context.ModifiedParent, context.CurrentParent = w.parents[j].modified, w.parents[j].current
+			break
+		}
+	}
+
+	return context
+}
@@ -0,0 +1,103 @@
+package jsonpatch_test
+
+import (
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+var _ = Describe("Decider", func() {
+	var (
+		modified G
+		current  G
+	)
+	BeforeEach(func() {
+		modified = G{C: C{Str: "a", StrMap: map[string]string{"a": "1", "b": "2"}}, D: D{StringSlice: []string{"x"}}}
+		current = G{C: C{Str: "b", StrMap: map[string]string{"a": "2"}}}
+	})
+
+	Context("WithDecider", func() {
+		It("should include all patches by default", func() {
+			testPatchWithExpected(modified, current, modified, jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(jsonpatch.DecisionContext) jsonpatch.Decision {
+				return jsonpatch.Include
+			})))
+		})
+		It("should skip values and subtrees", func() {
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(context jsonpatch.DecisionContext) jsonpatch.Decision {
+				switch context.Pointer.String() {
+				case "/c/strmap":
+					return jsonpatch.SkipSubtree
+				case "/d/strs":
+					return jsonpatch.Skip
+				}
+				return jsonpatch.Include
+			})))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "replace", Path: "/c/str", Value: "a"}}))
+		})
+		It("should skip single patches", func() {
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(context jsonpatch.DecisionContext) jsonpatch.Decision {
+				if context.Operation == "add" {
+					return jsonpatch.Skip
+				}
+				return jsonpatch.Include
+			})))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(ConsistOf(
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/c/str", Value: "a"},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/c/strmap/a", Value: "1"},
+			))
+		})
+		It("should replace values as a whole", func() {
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(context jsonpatch.DecisionContext) jsonpatch.Decision {
+				if context.Pointer.String() == "/c/strmap" {
+					return jsonpatch.ReplaceWhole
+				}
+				return jsonpatch.Include
+			})))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(ContainElement(jsonpatch.JSONPatch{Operation: "replace", Path: "/c/strmap", Value: modified.C.StrMap}))
+			Ω(list.Len()).Should(Equal(3))
+		})
+		It("should fail", func() {
+			_, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(context jsonpatch.DecisionContext) jsonpatch.Decision {
+				if context.Pointer.String() == "/c/strmap/a" {
+					return jsonpatch.Fail
+				}
+				return jsonpatch.Include
+			})))
+			Ω(err).Should(MatchError(ContainSubstring("/c/strmap/a")))
+
+			_, err = jsonpatch.CreateThreeWayJSONPatch(modified, current, current, jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(context jsonpatch.DecisionContext) jsonpatch.Decision {
+				if context.Pointer.String() == "/c" {
+					return jsonpatch.Fail
+				}
+				return jsonpatch.Include
+			})))
+			Ω(err).Should(MatchError(ContainSubstring("/c")))
+		})
+		It("should provide the context", func() {
+			var contexts []jsonpatch.DecisionContext
+			_, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(context jsonpatch.DecisionContext) jsonpatch.Decision {
+				if context.Pointer.String() == "/c/strmap/b" {
+					contexts = append(contexts, context)
+				}
+				return jsonpatch.Include
+			})))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(contexts).Should(HaveLen(1))
+			Ω(contexts[0].Operation).Should(Equal("add"))
+			Ω(contexts[0].Modified).Should(Equal("2"))
+			Ω(contexts[0].Current).Should(BeNil())
+			Ω(contexts[0].ModifiedParent).Should(Equal(modified.C.StrMap))
+			Ω(contexts[0].CurrentParent).Should(Equal(current.C.StrMap))
+			Ω(contexts[0].Depth).Should(Equal(3))
+			Ω(contexts[0].Kind).Should(Equal(reflect.String))
+			Ω(contexts[0].ModifiedRoot).Should(Equal(modified))
+			Ω(contexts[0].CurrentRoot).Should(Equal(current))
+		})
+	})
+})
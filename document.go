@@ -0,0 +1,162 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// decode converts a value into its generic JSON representation (maps, slices and scalars)
+func decode(value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	err = json.Unmarshal(raw, &doc)
+
+	return doc, err
+}
+
+// clone returns a deep copy of a generic JSON document
+func clone(doc interface{}) interface{} {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			c[key] = clone(value)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for j, value := range v {
+			c[j] = clone(value)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// unescape returns the unescaped reference token of a JSONPointer
+func unescape(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", separator), "~0", tilde)
+}
+
+// lookup returns the value at the pointer of a generic JSON document or false if it doesn't exist
+func lookup(doc interface{}, pointer JSONPointer) (interface{}, bool) {
+	for _, token := range pointer[1:] {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			value, ok := v[unescape(token)]
+			if !ok {
+				return nil, false
+			}
+			doc = value
+		case []interface{}:
+			j, err := strconv.Atoi(token)
+			if err != nil || j < 0 || j >= len(v) {
+				return nil, false
+			}
+			doc = v[j]
+		default:
+			return nil, false
+		}
+	}
+
+	return doc, true
+}
+
+// applyPatch applies a JSONPatch to a generic JSON document and returns the patched document. Maps and slices of the
+// document are modified in place.
+func applyPatch(doc interface{}, patch JSONPatch) (interface{}, error) {
+	pointer := ParseJSONPointer(patch.Path)
+	if pointer[0] != "" {
+		return nil, fmt.Errorf("invalid pointer at: %s", patch.Path)
+	}
+
+	var value interface{}
+	switch patch.Operation {
+	case "add", "replace":
+		var err error
+		if value, err = decode(patch.Value); err != nil {
+			return nil, err
+		}
+	case "remove":
+	case "test":
+		expected, err := decode(patch.Value)
+		if err != nil {
+			return nil, err
+		}
+		if actual, ok := lookup(doc, pointer); !ok || !reflect.DeepEqual(actual, expected) {
+			return nil, fmt.Errorf("test failed at: %s", patch.Path)
+		}
+		return doc, nil
+	case "move", "copy":
+		// a JSONPatch has no 'from' pointer
+		return nil, fmt.Errorf("unsupported operation without a from pointer: %s at: %s", patch.Operation, patch.Path)
+	default:
+		return nil, fmt.Errorf("unsupported operation: %s at: %s", patch.Operation, patch.Path)
+	}
+
+	return applyOperation(doc, pointer[1:], patch.Operation, value, patch.Path)
+}
+
+// applyOperation recursively applies an operation to the value referenced by the remaining tokens of a JSONPointer
+func applyOperation(doc interface{}, tokens []string, operation string, value interface{}, path string) (interface{}, error) {
+	if len(tokens) == 0 {
+		if operation == "remove" {
+			return nil, nil
+		}
+		return value, nil
+	}
+
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		key := unescape(tokens[0])
+		child, ok := v[key]
+		switch {
+		case len(tokens) > 1 && ok:
+			var err error
+			if v[key], err = applyOperation(child, tokens[1:], operation, value, path); err != nil {
+				return nil, err
+			}
+		case len(tokens) == 1 && operation == "add":
+			v[key] = value
+		case len(tokens) == 1 && operation == "replace" && ok:
+			v[key] = value
+		case len(tokens) == 1 && operation == "remove" && ok:
+			delete(v, key)
+		default:
+			return nil, fmt.Errorf("missing value at: %s", path)
+		}
+
+		return v, nil
+	case []interface{}:
+		j, err := strconv.Atoi(tokens[0])
+		if tokens[0] == "-" && len(tokens) == 1 && operation == "add" {
+			j, err = len(v), nil
+		}
+		switch {
+		case err != nil || j < 0 || j > len(v) || j == len(v) && (len(tokens) > 1 || operation != "add"):
+			return nil, fmt.Errorf("invalid index: %s at: %s", tokens[0], path)
+		case len(tokens) > 1:
+			if v[j], err = applyOperation(v[j], tokens[1:], operation, value, path); err != nil {
+				return nil, err
+			}
+		case operation == "add":
+			v = append(v[:j], append([]interface{}{value}, v[j:]...)...)
+		case operation == "replace":
+			v[j] = value
+		case operation == "remove":
+			v = append(v[:j], v[j+1:]...)
+		}
+
+		return v, nil
+	default:
+		return nil, fmt.Errorf("missing value at: %s", path)
+	}
+}
@@ -0,0 +1,289 @@
+package jsonpatch
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// comparableTypes caches whether the values of a type can be compared with ==
+var comparableTypes sync.Map // map[reflect.Type]bool
+
+// validTypes caches whether the values of a type can't contain struct fields with invalid jsonpatch tags
+var validTypes sync.Map // map[reflect.Type]bool
+
+// HashFunc returns a precomputed hash of a struct, pointer, slice or map value (e.g. a hash stored in an annotation) or
+// false if there is none
+type HashFunc func(value interface{}) (string, bool)
+
+// Equal reports whether the JSON data structures are equal according to the same rules as the patch creation, i.e.
+// whether CreateJSONPatch would create an empty patch with the same options
+func Equal(a, b interface{}, options ...Option) bool {
+	w := newWalker(options...)
+	w.modifiedRoot, w.currentRoot = a, b
+
+	return w.walk(reflect.ValueOf(a), reflect.ValueOf(b), reflect.Value{}, w.prefix) == nil && w.err() == nil && len(w.patchList) == 0
+}
+
+// identical reports whether the values are unchanged without walking them, i.e. they are the same pointer, slice or map,
+// they are equal comparable structs or they have the same precomputed hash. Without a Decider, which must decide about
+// the values whether they are shared or copies, the values are compared first.
+func (w *walker) identical(modified, current reflect.Value) bool {
+	switch modified.Kind() {
+	case reflect.Pointer, reflect.Map:
+		if w.decider == nil && modified.UnsafePointer() == current.UnsafePointer() && validType(modified.Type()) {
+			return true
+		}
+	case reflect.Slice:
+		if w.decider == nil && modified.Len() == current.Len() && modified.UnsafePointer() == current.UnsafePointer() && validType(modified.Type()) {
+			return true
+		}
+	case reflect.Struct:
+		if w.decider == nil && comparableType(modified.Type()) && validType(modified.Type()) && modified.Equal(current) {
+			return true
+		}
+	default:
+		return false
+	}
+
+	if w.hash != nil && modified.CanInterface() {
+		modifiedHash, ok := w.hash(modified.Interface())
+		if !ok {
+			return false
+		}
+		currentHash, ok := w.hash(current.Interface())
+
+		return ok && modifiedHash == currentHash
+	}
+
+	return false
+}
+
+// comparableType reports whether the values of a type can be compared with == without panicking and without skipping the
+// validation of the type
+func comparableType(t reflect.Type) bool {
+	if c, ok := comparableTypes.Load(t); ok {
+		return c.(bool)
+	}
+
+	c := t.Comparable()
+	switch t.Kind() {
+	case reflect.Interface, reflect.Chan, reflect.UnsafePointer:
+		// interfaces might contain values which aren't comparable and channels aren't supported
+		c = false
+	case reflect.Struct:
+		for j := 0; j < t.NumField() && c; j++ {
+			c = comparableType(t.Field(j).Type)
+		}
+		// invalid jsonpatch tags must still fail the patch creation
+		for _, field := range structInfoOf(t).fields {
+			c = c && !field.invalid
+		}
+	case reflect.Array:
+		c = c && comparableType(t.Elem())
+	}
+	comparableTypes.Store(t, c)
+
+	return c
+}
+
+// validType reports whether the values of a type can be walked without failing due to their type, i.e. they can't
+// contain struct fields with invalid jsonpatch tags, maps with keys which aren't strings or values of unsupported kinds.
+// The values of interfaces are validated while they are walked.
+func validType(t reflect.Type) bool {
+	if v, ok := validTypes.Load(t); ok {
+		return v.(bool)
+	}
+
+	// only the result of the type itself is cached, the results of recursive types are incomplete until then
+	v := validTypeOf(t, map[reflect.Type]bool{})
+	validTypes.Store(t, v)
+
+	return v
+}
+
+// validTypeOf validates a type, types which are already visited are valid unless proven otherwise by their first visit
+func validTypeOf(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if v, ok := validTypes.Load(t); ok {
+		return v.(bool)
+	}
+	if visited[t] {
+		return true
+	}
+	visited[t] = true
+
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && validTypeOf(t.Elem(), visited)
+	case reflect.Pointer, reflect.Slice:
+		return validTypeOf(t.Elem(), visited)
+	case reflect.Array, reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return false
+	case reflect.Struct:
+		for _, field := range structInfoOf(t).fields {
+			if field.invalid || !validTypeOf(t.Field(field.index).Type, visited) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// valueKey is the canonical key of a value, see walker.keyOf
+type valueKey struct {
+	key string
+	ok  bool
+}
+
+// differs reports whether the walker would patch the value of the key k to the value of the key o
+func (k valueKey) differs(o valueKey) bool {
+	return !k.ok || !o.ok || k.key != o.key
+}
+
+// keyOf returns the canonical key of a value, the keys of two values are equal if the walker doesn't create a patch
+// between them: nil and empty slices and maps have the same key, and the elements of unordered slices are sorted. Values
+// which can't be walked (e.g. maps with keys which aren't strings) have no key, they differ from all other values. An
+// invalid value (i.e. a value which is not part of the JSON) has an empty key.
+func (w *walker) keyOf(v reflect.Value) valueKey {
+	if !v.IsValid() {
+		return valueKey{ok: true}
+	}
+	var b strings.Builder
+	ok := w.writeKey(&b, v, nil)
+
+	return valueKey{key: b.String(), ok: ok}
+}
+
+// keysOf returns the canonical keys of the elements of a slice
+func (w *walker) keysOf(v reflect.Value) []valueKey {
+	keys := make([]valueKey, v.Len())
+	for j := range keys {
+		keys[j] = w.keyOf(v.Index(j))
+	}
+
+	return keys
+}
+
+// writeKey writes the canonical key of a value at the pointer relative to the compared values, it returns false if the
+// value can't be walked
+func (w *walker) writeKey(b *strings.Builder, v reflect.Value, pointer JSONPointer) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		b.WriteByte('n')
+	case reflect.Interface:
+		if !v.CanInterface() {
+			return false
+		}
+		return w.writeKey(b, reflect.ValueOf(v.Interface()), pointer)
+	case reflect.Pointer:
+		if v.IsNil() {
+			b.WriteByte('p')
+			return true
+		}
+		b.WriteByte('*')
+		return w.writeKey(b, v.Elem(), pointer)
+	case reflect.Struct:
+		if v.Type().PkgPath() == "time" && v.Type().Name() == "Time" {
+			t, err := toTimeStrValue(v)
+			if err != nil {
+				return false
+			}
+			b.WriteString(strconv.Quote(t.String()))
+			return true
+		}
+		b.WriteString("s" + v.Type().String() + "{")
+		for _, field := range structInfoOf(v.Type()).fields {
+			options, err := field.optionsAt(pointer.Add(field.name))
+			if err != nil {
+				return false
+			} else if options.ignore {
+				continue
+			}
+			b.WriteString(strconv.Quote(field.name) + ":")
+			if !w.writeFieldKey(b, v.Field(field.index), pointer.Add(field.name), options) {
+				return false
+			}
+			b.WriteByte(',')
+		}
+		b.WriteByte('}')
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return false
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		b.WriteString("m{")
+		for _, key := range keys {
+			b.WriteString(strconv.Quote(key.String()) + ":")
+			if !w.writeKey(b, v.MapIndex(key), pointer.Add(key.String())) {
+				return false
+			}
+			b.WriteByte(',')
+		}
+		b.WriteByte('}')
+	case reflect.Slice:
+		b.WriteString("a[")
+		if ignore, ok := w.ignoredSliceOf(pointer); ok {
+			// the elements are matched by their JSON field, which must be unique
+			if _, err := indexSliceElements(v, jsonFieldNameToFieldIndex(v.Type().Elem(), ignore.JSONField), pointer); err != nil {
+				return false
+			}
+			keys := make([]string, v.Len())
+			for j := range keys {
+				var e strings.Builder
+				if !w.writeKey(&e, v.Index(j), pointer.Add(strconv.Itoa(j))) {
+					return false
+				}
+				keys[j] = e.String()
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				b.WriteString(key + ",")
+			}
+		} else {
+			for j := 0; j < v.Len(); j++ {
+				if !w.writeKey(b, v.Index(j), pointer.Add(strconv.Itoa(j))) {
+					return false
+				}
+				b.WriteByte(',')
+			}
+		}
+		b.WriteByte(']')
+	case reflect.String:
+		b.WriteString(strconv.Quote(v.String()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b.WriteString(strconv.Itoa(int(v.Kind())) + ":" + strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uintptr, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b.WriteString(strconv.Itoa(int(v.Kind())) + ":" + strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f == 0 {
+			// -0 equals 0
+			f = 0
+		}
+		b.WriteString(strconv.Itoa(int(v.Kind())) + ":" + strconv.FormatFloat(f, 'g', -1, v.Type().Bits()))
+	case reflect.Bool:
+		b.WriteString(strconv.Itoa(int(v.Kind())) + ":" + strconv.FormatBool(v.Bool()))
+	default:
+		return false
+	}
+
+	return true
+}
+
+// writeFieldKey writes the canonical key of the value of a struct field, the options of the field are only applied to
+// the value itself (see walker.walkField)
+func (w *walker) writeFieldKey(b *strings.Builder, v reflect.Value, pointer JSONPointer, options fieldOptions) bool {
+	if options.unordered && (!options.merge || uniqueElements(options.key, pointer, v)) {
+		ignoredSlices := w.ignoredSlices
+		w.ignoredSlices = append([]IgnorePattern{{Pattern: pointer.String(), JSONField: options.key}}, ignoredSlices...)
+		defer func() { w.ignoredSlices = ignoredSlices }()
+	}
+
+	return w.writeKey(b, v, pointer)
+}
@@ -0,0 +1,118 @@
+package jsonpatch_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+var _ = Describe("Equal", func() {
+	var (
+		modified G
+		current  G
+	)
+	BeforeEach(func() {
+		modified = G{B: &B{Str: "str"}, C: C{Str: "str", StrMap: map[string]string{"a": "1"}}, D: D{StringSlice: []string{"x"}}}
+		current = G{B: &B{Str: "str"}, C: C{Str: "str", StrMap: map[string]string{"a": "1"}}, D: D{StringSlice: []string{"x"}}}
+	})
+
+	Context("Equal", func() {
+		It("should compare the JSON representation", func() {
+			Ω(jsonpatch.Equal(modified, current)).Should(BeTrue())
+			Ω(jsonpatch.Equal(modified, modified)).Should(BeTrue())
+			Ω(jsonpatch.Equal(&B{Int: 1}, &B{Int: 1})).Should(BeTrue())
+
+			current.C.StrMap["a"] = "2"
+			Ω(jsonpatch.Equal(modified, current)).Should(BeFalse())
+			Ω(jsonpatch.Equal(B{Int: 1}, B{Int: 2})).Should(BeFalse())
+			Ω(jsonpatch.Equal([]string{"a"}, []string(nil))).Should(BeFalse())
+		})
+		It("should not be equal for different types", func() {
+			Ω(jsonpatch.Equal(A{}, B{})).Should(BeFalse())
+			Ω(jsonpatch.Equal(I{1}, I{"1"})).Should(BeFalse())
+		})
+		It("should use the options", func() {
+			current.C.StrMap["a"] = "2"
+			Ω(jsonpatch.Equal(modified, current, jsonpatch.WithPredicate(jsonpatch.ExcludePaths("/c/strmap")))).Should(BeTrue())
+			Ω(jsonpatch.Equal([]int{1, 2}, []int{2, 1}, jsonpatch.IgnoreSliceOrder())).Should(BeTrue())
+		})
+	})
+	Context("identical values", func() {
+		var walked []string
+		var predicate jsonpatch.Predicate
+		BeforeEach(func() {
+			walked = nil
+			predicate = jsonpatch.Funcs{
+				ReplaceFunc: func(pointer jsonpatch.JSONPointer, _, _ interface{}) bool {
+					walked = append(walked, pointer.String())
+					return true
+				},
+			}
+		})
+		It("should not walk the same pointers, slices and maps", func() {
+			current.B, current.C.StrMap, current.D.StringSlice = modified.B, modified.C.StrMap, modified.D.StringSlice
+			current.C.Str = "old"
+			testPatchWithExpected(modified, current, modified, jsonpatch.WithPredicate(predicate))
+			Ω(walked).Should(ConsistOf("", "/c", "/c/str", "/d"))
+		})
+		It("should walk the same pointers, slices and maps with a decider", func() {
+			shared := current
+			shared.B, shared.C.StrMap = modified.B, modified.C.StrMap
+			decider := jsonpatch.DeciderFunc(func(context jsonpatch.DecisionContext) jsonpatch.Decision {
+				if context.Pointer.String() == "/c/strmap" {
+					return jsonpatch.Fail
+				}
+				return jsonpatch.Include
+			})
+			_, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithDecider(decider))
+			Ω(err).Should(MatchError(jsonpatch.ErrDecisionFailed))
+			_, err = jsonpatch.CreateJSONPatch(modified, shared, jsonpatch.WithDecider(decider))
+			Ω(err).Should(MatchError(jsonpatch.ErrDecisionFailed))
+			Ω(jsonpatch.Equal(modified, current, jsonpatch.WithDecider(decider))).Should(BeFalse())
+			Ω(jsonpatch.Equal(modified, shared, jsonpatch.WithDecider(decider))).Should(BeFalse())
+		})
+		It("should walk the same pointers, slices and maps with invalid tags", func() {
+			type invalid struct {
+				Str string `json:"str" jsonpatch:"unknown"`
+			}
+			value := &invalid{}
+			_, err := jsonpatch.CreateJSONPatch(struct {
+				Value *invalid `json:"value"`
+			}{value}, struct {
+				Value *invalid `json:"value"`
+			}{value})
+			Ω(err).Should(HaveOccurred())
+
+			values := []invalid{{}}
+			_, err = jsonpatch.CreateJSONPatch(values, values)
+			Ω(err).Should(HaveOccurred())
+		})
+		It("should walk the same maps with invalid keys", func() {
+			shared := map[int]string{1: "a"}
+			_, err := jsonpatch.CreateJSONPatch(shared, map[int]string{1: "a"})
+			Ω(err).Should(MatchError(ContainSubstring("only strings are supported as map keys")))
+			_, err = jsonpatch.CreateJSONPatch(shared, shared)
+			Ω(err).Should(MatchError(ContainSubstring("only strings are supported as map keys")))
+			_, err = jsonpatch.CreateJSONPatch(&shared, &shared)
+			Ω(err).Should(MatchError(ContainSubstring("only strings are supported as map keys")))
+			Ω(jsonpatch.Equal(shared, shared)).Should(BeFalse())
+		})
+		It("should not walk equal comparable structs", func() {
+			testPatchWithExpected(G{E: E{Exported: 1}}, G{E: E{Exported: 1}}, G{E: E{Exported: 1}}, jsonpatch.WithPredicate(predicate))
+			Ω(walked).ShouldNot(ContainElement("/e"))
+		})
+		It("should not walk values with the same hash", func() {
+			hash := jsonpatch.WithHashFunc(func(value interface{}) (string, bool) {
+				if c, ok := value.(C); ok {
+					return c.Str, true
+				}
+				return "", false
+			})
+			current.C.StrMap["a"] = "2"
+			Ω(jsonpatch.Equal(modified, current, hash)).Should(BeTrue())
+			current.C.Str = "old"
+			Ω(jsonpatch.Equal(modified, current, hash)).Should(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,182 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// FieldSet is the set of fields which are set by an object, e.g. the fields which are owned by a field manager
+type FieldSet struct {
+	pointers map[string]JSONPointer
+	fieldsV1 map[string]interface{}
+}
+
+// NewFieldSet creates the set of fields which are set by the value (i.e. which aren't null). Lists are tracked as a
+// whole, the elements of unordered lists are only distinguished by their merge key (or value) in the Kubernetes
+// 'fieldsV1' format.
+func NewFieldSet(value interface{}, options ...Option) (FieldSet, error) {
+	w := newWalker(options...)
+
+	doc, err := decode(value)
+	if err != nil {
+		return FieldSet{}, err
+	}
+	object, ok := doc.(map[string]interface{})
+	if !ok {
+		return FieldSet{}, fmt.Errorf("field sets are only supported for objects at: %s", JSONPointer(w.prefix))
+	}
+
+	set := FieldSet{pointers: map[string]JSONPointer{}}
+	if set.fieldsV1, err = w.fieldsOf(object, reflect.TypeOf(value), w.prefix, set.pointers); err != nil {
+		return FieldSet{}, err
+	}
+
+	return set, nil
+}
+
+// Pointers returns the sorted JSONPointers of all fields in the FieldSet
+func (s FieldSet) Pointers() []JSONPointer {
+	keys := make([]string, 0, len(s.pointers))
+	for key := range s.pointers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pointers := make([]JSONPointer, len(keys))
+	for j, key := range keys {
+		pointers[j] = s.pointers[key]
+	}
+
+	return pointers
+}
+
+// Has returns true if the field at the pointer (or one of its parents) is part of the FieldSet
+func (s FieldSet) Has(pointer JSONPointer) bool {
+	for j := len(pointer); j > 0; j-- {
+		if _, ok := s.pointers[pointer[:j].String()]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FieldsV1 returns the FieldSet encoded in the Kubernetes 'fieldsV1' format of managed fields
+func (s FieldSet) FieldsV1() ([]byte, error) {
+	if s.fieldsV1 == nil {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(s.fieldsV1)
+}
+
+// Predicate returns a Predicate which restricts the patch to the fields of the FieldSet. Parents of the fields are
+// accepted as well, in order to walk into them.
+func (s FieldSet) Predicate() Predicate {
+	accept := func(pointer JSONPointer) bool {
+		if s.Has(pointer) {
+			return true
+		}
+		for _, field := range s.pointers {
+			if hasPrefix(field, pointer) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return Funcs{
+		AddFunc: func(pointer JSONPointer, _ interface{}) bool {
+			return accept(pointer)
+		},
+		RemoveFunc: func(pointer JSONPointer, _ interface{}) bool {
+			return accept(pointer)
+		},
+		ReplaceFunc: func(pointer JSONPointer, _, _ interface{}) bool {
+			return accept(pointer)
+		},
+	}
+}
+
+// fieldsOf adds the fields of an object of the type t to the pointers and returns them in the 'fieldsV1' format
+func (w *walker) fieldsOf(object map[string]interface{}, t reflect.Type, pointer JSONPointer, pointers map[string]JSONPointer) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	for key, value := range object {
+		if value == nil {
+			continue
+		}
+
+		child := pointer.Add(key)
+		childType, options, err := w.schemaOf(t, key, child)
+		if err != nil {
+			return nil, err
+		}
+		if options.ignore {
+			continue
+		}
+
+		pointers[child.String()] = append(JSONPointer{}, child...)
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if len(v) > 0 && !options.atomic {
+				delete(pointers, child.String())
+				if fields["f:"+key], err = w.fieldsOf(v, childType, child, pointers); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		case []interface{}:
+			if !options.atomic {
+				if fields["f:"+key], err = w.listFieldsOf(v, elemTypeOf(childType), options, child); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		fields["f:"+key] = map[string]interface{}{}
+	}
+
+	return fields, nil
+}
+
+// listFieldsOf returns the elements of a list in the 'fieldsV1' format, they are identified by their merge key, their
+// value (for unordered lists of primitives) or their index
+func (w *walker) listFieldsOf(list []interface{}, t reflect.Type, options fieldOptions, pointer JSONPointer) (map[string]interface{}, error) {
+	elements := map[string]interface{}{}
+	for j, element := range list {
+		var name string
+		object, isObject := element.(map[string]interface{})
+		switch _, isList := element.([]interface{}); {
+		case options.unordered && options.key != "" && isObject:
+			raw, err := json.Marshal(map[string]interface{}{options.key: object[options.key]})
+			if err != nil {
+				return nil, err
+			}
+			name = "k:" + string(raw)
+		case options.unordered && options.key == "" && !isObject && !isList:
+			raw, err := json.Marshal(element)
+			if err != nil {
+				return nil, err
+			}
+			name = "v:" + string(raw)
+		default:
+			name = "i:" + strconv.Itoa(j)
+		}
+
+		fields := map[string]interface{}{}
+		if isObject {
+			// the fields of the elements are only part of the 'fieldsV1' format, lists are tracked as a whole
+			var err error
+			if fields, err = w.fieldsOf(object, t, pointer.Add(strconv.Itoa(j)), map[string]JSONPointer{}); err != nil {
+				return nil, err
+			}
+			fields["."] = map[string]interface{}{}
+		}
+		elements[name] = fields
+	}
+
+	return elements, nil
+}
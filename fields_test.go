@@ -0,0 +1,81 @@
+package jsonpatch_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+var _ = Describe("FieldSet", func() {
+	var value N
+	BeforeEach(func() {
+		value = N{
+			Spec: L{
+				Containers: []M{{Name: "main", Image: "a"}},
+				Finalizers: []string{"x"},
+				Selector:   map[string]string{"app": "a"},
+			},
+			Labels: map[string]string{"a": "1"},
+			Status: "ready",
+			Args:   []string{"y"},
+		}
+	})
+
+	Context("NewFieldSet", func() {
+		It("should contain all fields which are set", func() {
+			set, err := jsonpatch.NewFieldSet(value)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(set.Pointers()).Should(Equal([]jsonpatch.JSONPointer{
+				jsonpatch.ParseJSONPointer("/args"),
+				jsonpatch.ParseJSONPointer("/labels/a"),
+				jsonpatch.ParseJSONPointer("/spec/containers"),
+				jsonpatch.ParseJSONPointer("/spec/finalizers"),
+				jsonpatch.ParseJSONPointer("/spec/selector"),
+			}))
+			Ω(set.Has(jsonpatch.ParseJSONPointer("/spec/containers/0/image"))).Should(BeTrue())
+			Ω(set.Has(jsonpatch.ParseJSONPointer("/labels/b"))).Should(BeFalse())
+			Ω(set.Has(jsonpatch.ParseJSONPointer("/status"))).Should(BeFalse())
+		})
+		It("should encode the fields in the fieldsV1 format", func() {
+			set, err := jsonpatch.NewFieldSet(value)
+			Ω(err).ShouldNot(HaveOccurred())
+			fieldsV1, err := set.FieldsV1()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(fieldsV1).Should(MatchJSON(`{
+				"f:args":{"i:0":{}},
+				"f:labels":{"f:a":{}},
+				"f:spec":{
+					"f:containers":{"k:{\"name\":\"main\"}":{".":{},"f:name":{},"f:image":{}}},
+					"f:finalizers":{"v:\"x\"":{}},
+					"f:selector":{}
+				}
+			}`))
+
+			fieldsV1, err = jsonpatch.FieldSet{}.FieldsV1()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(fieldsV1).Should(MatchJSON(`{}`))
+		})
+		It("should fail for values which aren't objects", func() {
+			_, err := jsonpatch.NewFieldSet([]string{"a"})
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+	Context("Predicate", func() {
+		It("should restrict the patch to the fields of the set", func() {
+			owned, err := jsonpatch.NewFieldSet(N{Labels: map[string]string{"a": "1"}, Spec: L{Containers: []M{{Name: "main"}}}})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			modified := value
+			modified.Labels = map[string]string{"a": "2", "b": "2"}
+			modified.Args = []string{"z"}
+			modified.Spec.Containers = []M{{Name: "main", Image: "b"}}
+			list, err := jsonpatch.CreateJSONPatch(modified, value, jsonpatch.WithPredicate(owned.Predicate()))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(ConsistOf(
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/labels/a", Value: "2"},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/spec/containers/0/image", Value: "b"},
+			))
+		})
+	})
+})
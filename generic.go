@@ -0,0 +1,126 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+)
+
+// CreateJSONPatchT creates a JSONPatch like CreateJSONPatch, but requires the modified and current JSON data structures
+// to be of the same type at compile time
+func CreateJSONPatchT[T any](modified, current T, options ...Option) (JSONPatchList, error) {
+	return CreateJSONPatch(modified, current, options...)
+}
+
+// CreateThreeWayJSONPatchT creates a three-way JSONPatch like CreateThreeWayJSONPatch, but requires the modified, current
+// and original JSON data structures to be of the same type at compile time
+func CreateThreeWayJSONPatchT[T any](modified, current, original T, options ...Option) (JSONPatchList, error) {
+	return CreateThreeWayJSONPatch(modified, current, original, options...)
+}
+
+// ApplyPatchT applies the JSONPatch to the JSON encoding of the value and returns the patched value, which is decoded
+// from the patched JSON. Therefore, fields which aren't part of the JSON (e.g. unexported fields or fields with the JSON
+// tag '-') are zero in the patched value. The operations 'add', 'remove', 'replace' and 'test' are supported, a failed
+// 'test' fails the whole patch. The operations 'move' and 'copy' aren't supported, because a JSONPatch has no 'from'
+// pointer.
+func ApplyPatchT[T any](value T, list JSONPatchList) (T, error) {
+	var patched T
+	doc, err := decode(value)
+	if err != nil {
+		return patched, err
+	}
+	for _, patch := range list.list {
+		if doc, err = applyPatch(doc, patch); err != nil {
+			return patched, err
+		}
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return patched, err
+	}
+	err = json.Unmarshal(raw, &patched)
+
+	return patched, err
+}
+
+// TypedFuncs is a function that implements Predicate for values of the type T, values of other types are accepted
+type TypedFuncs[T any] struct {
+	// AddFunc returns true if the object should be added in the patch
+	AddFunc func(pointer JSONPointer, modified T) bool
+
+	// RemoveFunc returns true if the object should be deleted in the patch
+	RemoveFunc func(pointer JSONPointer, current T) bool
+
+	// ReplaceFunc returns true if the objects should be updated in the patch - returning false will stop the recursive processing of those objects
+	ReplaceFunc func(pointer JSONPointer, modified, current T) bool
+}
+
+// Add implements Predicate
+func (p TypedFuncs[T]) Add(pointer JSONPointer, modified interface{}) bool {
+	if m, ok := modified.(T); ok && p.AddFunc != nil {
+		return p.AddFunc(pointer, m)
+	}
+
+	return true
+}
+
+// Remove implements Predicate
+func (p TypedFuncs[T]) Remove(pointer JSONPointer, current interface{}) bool {
+	if c, ok := current.(T); ok && p.RemoveFunc != nil {
+		return p.RemoveFunc(pointer, c)
+	}
+
+	return true
+}
+
+// Replace implements Predicate
+func (p TypedFuncs[T]) Replace(pointer JSONPointer, modified, current interface{}) bool {
+	m, okModified := modified.(T)
+	c, okCurrent := current.(T)
+	if okModified && okCurrent && p.ReplaceFunc != nil {
+		return p.ReplaceFunc(pointer, m, c)
+	}
+
+	return true
+}
+
+// TypedHandlerFuncs is a function that implements Handler for values of the type T, the DefaultHandler is used for
+// values of other types and for nil functions
+type TypedHandlerFuncs[T any] struct {
+	// AddFunc creates the patches for an 'add' operation
+	AddFunc func(pointer JSONPointer, modified T) []JSONPatch
+
+	// RemoveFunc creates the patches for a 'remove' operation
+	RemoveFunc func(pointer JSONPointer, current T) []JSONPatch
+
+	// ReplaceFunc creates the patches for a 'replace' operation
+	ReplaceFunc func(pointer JSONPointer, modified, current T) []JSONPatch
+}
+
+// Add implements Handler
+func (h TypedHandlerFuncs[T]) Add(pointer JSONPointer, modified interface{}) []JSONPatch {
+	if m, ok := modified.(T); ok && h.AddFunc != nil {
+		return h.AddFunc(pointer, m)
+	}
+
+	return (&DefaultHandler{}).Add(pointer, modified)
+}
+
+// Remove implements Handler
+func (h TypedHandlerFuncs[T]) Remove(pointer JSONPointer, current interface{}) []JSONPatch {
+	if c, ok := current.(T); ok && h.RemoveFunc != nil {
+		return h.RemoveFunc(pointer, c)
+	}
+
+	return (&DefaultHandler{}).Remove(pointer, current)
+}
+
+// Replace implements Handler
+func (h TypedHandlerFuncs[T]) Replace(pointer JSONPointer, modified, current interface{}) []JSONPatch {
+	m, okModified := modified.(T)
+	c, okCurrent := current.(T)
+	if okModified && okCurrent && h.ReplaceFunc != nil {
+		return h.ReplaceFunc(pointer, m, c)
+	}
+
+	return (&DefaultHandler{}).Replace(pointer, modified, current)
+}
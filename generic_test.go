@@ -0,0 +1,120 @@
+package jsonpatch_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+var _ = Describe("Generics", func() {
+	var (
+		modified G
+		current  G
+	)
+	BeforeEach(func() {
+		modified = G{B: &B{Str: "new", Int: 1}, C: C{Str: "new", StrMap: map[string]string{"a": "1"}}}
+		current = G{B: &B{Str: "old"}, C: C{Str: "old", StrMap: map[string]string{"b": "2"}}}
+	})
+
+	Context("CreateJSONPatchT", func() {
+		It("should create the patch", func() {
+			list, err := jsonpatch.CreateJSONPatchT(modified, current)
+			Ω(err).ShouldNot(HaveOccurred())
+			patched, err := jsonpatch.ApplyPatchT(current, list)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(patched).Should(Equal(modified))
+		})
+		It("should create the three-way patch", func() {
+			list, err := jsonpatch.CreateThreeWayJSONPatchT(modified, current, current)
+			Ω(err).ShouldNot(HaveOccurred())
+			patched, err := jsonpatch.ApplyPatchT(current, list)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(patched).Should(Equal(modified))
+		})
+	})
+	Context("ApplyPatchT", func() {
+		It("should not modify the value", func() {
+			list, err := jsonpatch.CreateJSONPatchT(modified, current)
+			Ω(err).ShouldNot(HaveOccurred())
+			_, err = jsonpatch.ApplyPatchT(current, list)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(current.C.StrMap).Should(Equal(map[string]string{"b": "2"}))
+		})
+		It("should fail for invalid patches", func() {
+			list, err := jsonpatch.NewJSONPatchList([]jsonpatch.JSONPatch{{Operation: "remove", Path: "/c/strmap/x"}})
+			Ω(err).ShouldNot(HaveOccurred())
+			_, err = jsonpatch.ApplyPatchT(current, list)
+			Ω(err).Should(HaveOccurred())
+		})
+		It("should apply test operations", func() {
+			list, err := jsonpatch.NewJSONPatchList([]jsonpatch.JSONPatch{
+				{Operation: "test", Path: "/c/strmap", Value: map[string]string{"b": "2"}},
+				{Operation: "replace", Path: "/c/strmap/b", Value: "3"},
+				{Operation: "test", Path: "/c/strmap/b", Value: "3"},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			patched, err := jsonpatch.ApplyPatchT(current, list)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(patched.C.StrMap).Should(Equal(map[string]string{"b": "3"}))
+
+			list, err = jsonpatch.NewJSONPatchList([]jsonpatch.JSONPatch{{Operation: "test", Path: "/c/strmap/b", Value: "3"}})
+			Ω(err).ShouldNot(HaveOccurred())
+			_, err = jsonpatch.ApplyPatchT(current, list)
+			Ω(err).Should(MatchError("test failed at: /c/strmap/b"))
+		})
+		It("should decode the patched value from the JSON", func() {
+			list, err := jsonpatch.CreateJSONPatchT(E{Exported: 2}, E{Exported: 1})
+			Ω(err).ShouldNot(HaveOccurred())
+			patched, err := jsonpatch.ApplyPatchT(E{unexported: 1, Exported: 1}, list)
+			Ω(err).ShouldNot(HaveOccurred())
+			// the unexported field isn't part of the JSON
+			Ω(patched).Should(Equal(E{Exported: 2}))
+		})
+		It("should fail for move and copy operations", func() {
+			list, err := jsonpatch.NewJSONPatchList([]jsonpatch.JSONPatch{{Operation: "move", Path: "/c/strmap/x"}})
+			Ω(err).ShouldNot(HaveOccurred())
+			_, err = jsonpatch.ApplyPatchT(current, list)
+			Ω(err).Should(MatchError(ContainSubstring("unsupported operation without a from pointer: move")))
+		})
+	})
+	Context("TypedFuncs", func() {
+		It("should only filter values of the type", func() {
+			list, err := jsonpatch.CreateJSONPatchT(modified, current, jsonpatch.WithPredicate(jsonpatch.TypedFuncs[string]{
+				AddFunc: func(_ jsonpatch.JSONPointer, modified string) bool {
+					return modified != "1"
+				},
+				ReplaceFunc: func(_ jsonpatch.JSONPointer, modified, _ string) bool {
+					return modified != "new"
+				},
+			}))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(ConsistOf(
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/b/int", Value: int64(1)},
+				jsonpatch.JSONPatch{Operation: "remove", Path: "/c/strmap/b"},
+			))
+		})
+	})
+	Context("TypedHandlerFuncs", func() {
+		It("should only handle values of the type", func() {
+			list, err := jsonpatch.CreateJSONPatchT(modified, current, jsonpatch.WithHandler(jsonpatch.TypedHandlerFuncs[string]{
+				ReplaceFunc: func(pointer jsonpatch.JSONPointer, modified, current string) []jsonpatch.JSONPatch {
+					return []jsonpatch.JSONPatch{
+						{Operation: "test", Path: pointer.String(), Value: current},
+						{Operation: "replace", Path: pointer.String(), Value: modified},
+					}
+				},
+			}))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(ConsistOf(
+				jsonpatch.JSONPatch{Operation: "test", Path: "/b/str", Value: "old"},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/b/str", Value: "new"},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/b/int", Value: int64(1)},
+				jsonpatch.JSONPatch{Operation: "test", Path: "/c/str", Value: "old"},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/c/str", Value: "new"},
+				jsonpatch.JSONPatch{Operation: "add", Path: "/c/strmap/a", Value: "1"},
+				jsonpatch.JSONPatch{Operation: "remove", Path: "/c/strmap/b"},
+			))
+		})
+	})
+})
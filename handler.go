@@ -1,5 +1,9 @@
 package jsonpatch
 
+import (
+	"context"
+)
+
 // Handler is the interfaces used by the walker to create patches
 type Handler interface {
 	// Add creates a JSONPatch with an 'add' operation and appends it to the patch list
@@ -12,6 +16,15 @@ type Handler interface {
 	Replace(pointer JSONPointer, modified, current interface{}) []JSONPatch
 }
 
+// ContextHandler is a Handler which is bound to the context of the patch creation (see CreateJSONPatchContext) by
+// WithHandler, the walker uses the returned Handler instead
+type ContextHandler interface {
+	Handler
+
+	// WithContext returns the Handler which is used for the patch creation with the context
+	WithContext(ctx context.Context) Handler
+}
+
 // DefaultHandler implements the Handler
 type DefaultHandler struct{}
 
@@ -50,3 +63,106 @@ func (h *DefaultHandler) Replace(pointer JSONPointer, value, _ interface{}) []JS
 		},
 	}
 }
+
+// HandlerFuncs is a function that implements Handler, the DefaultHandler is used for nil functions
+type HandlerFuncs struct {
+	// AddFunc creates the patches for an 'add' operation
+	AddFunc func(pointer JSONPointer, modified interface{}) []JSONPatch
+
+	// RemoveFunc creates the patches for a 'remove' operation
+	RemoveFunc func(pointer JSONPointer, current interface{}) []JSONPatch
+
+	// ReplaceFunc creates the patches for a 'replace' operation
+	ReplaceFunc func(pointer JSONPointer, modified, current interface{}) []JSONPatch
+}
+
+// Add implements Handler
+func (h HandlerFuncs) Add(pointer JSONPointer, modified interface{}) []JSONPatch {
+	if h.AddFunc != nil {
+		return h.AddFunc(pointer, modified)
+	}
+
+	return (&DefaultHandler{}).Add(pointer, modified)
+}
+
+// Remove implements Handler
+func (h HandlerFuncs) Remove(pointer JSONPointer, current interface{}) []JSONPatch {
+	if h.RemoveFunc != nil {
+		return h.RemoveFunc(pointer, current)
+	}
+
+	return (&DefaultHandler{}).Remove(pointer, current)
+}
+
+// Replace implements Handler
+func (h HandlerFuncs) Replace(pointer JSONPointer, modified, current interface{}) []JSONPatch {
+	if h.ReplaceFunc != nil {
+		return h.ReplaceFunc(pointer, modified, current)
+	}
+
+	return (&DefaultHandler{}).Replace(pointer, modified, current)
+}
+
+// HandlerMiddleware wraps the next Handler in order to layer a behaviour on top of it (e.g. logging or redaction)
+type HandlerMiddleware func(next Handler) Handler
+
+// ChainHandlers creates a Handler by wrapping the DefaultHandler with the middlewares, the first middleware is the
+// outermost one and is called first
+func ChainHandlers(middlewares ...HandlerMiddleware) Handler {
+	var handler Handler = &DefaultHandler{}
+	for j := len(middlewares) - 1; j >= 0; j-- {
+		handler = middlewares[j](handler)
+	}
+
+	return handler
+}
+
+// ErrorHandler creates patches like a Handler, but can abort the patch creation with an error. The error is returned by
+// the patch creation together with the pointer.
+type ErrorHandler interface {
+	// Add creates a JSONPatch with an 'add' operation and appends it to the patch list
+	Add(pointer JSONPointer, modified interface{}) ([]JSONPatch, error)
+
+	// Remove creates a JSONPatch with an 'remove' operation and appends it to the patch list
+	Remove(pointer JSONPointer, current interface{}) ([]JSONPatch, error)
+
+	// Replace creates a JSONPatch with an 'replace' operation and appends it to the patch list
+	Replace(pointer JSONPointer, modified, current interface{}) ([]JSONPatch, error)
+}
+
+// errorHandler adapts an ErrorHandler to a Handler which records the errors in the walker
+type errorHandler struct {
+	handler ErrorHandler
+	walker  *walker
+}
+
+// Add implements Handler
+func (h *errorHandler) Add(pointer JSONPointer, modified interface{}) []JSONPatch {
+	patches, err := h.handler.Add(pointer, modified)
+
+	return h.check(pointer, patches, err)
+}
+
+// Remove implements Handler
+func (h *errorHandler) Remove(pointer JSONPointer, current interface{}) []JSONPatch {
+	patches, err := h.handler.Remove(pointer, current)
+
+	return h.check(pointer, patches, err)
+}
+
+// Replace implements Handler
+func (h *errorHandler) Replace(pointer JSONPointer, modified, current interface{}) []JSONPatch {
+	patches, err := h.handler.Replace(pointer, modified, current)
+
+	return h.check(pointer, patches, err)
+}
+
+// check records the error and drops the patches if there is one
+func (h *errorHandler) check(pointer JSONPointer, patches []JSONPatch, err error) []JSONPatch {
+	if err != nil {
+		h.walker.fail(pointer, err)
+		return nil
+	}
+
+	return patches
+}
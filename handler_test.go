@@ -0,0 +1,94 @@
+package jsonpatch_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+var _ = Describe("Handler", func() {
+	var (
+		modified G
+		current  G
+	)
+	BeforeEach(func() {
+		modified = G{C: C{Str: "new", StrMap: map[string]string{"a": "1"}}}
+		current = G{C: C{Str: "old", StrMap: map[string]string{"b": "2"}}}
+	})
+
+	Context("HandlerFuncs", func() {
+		It("should use the DefaultHandler by default", func() {
+			testPatchWithExpected(modified, current, modified, jsonpatch.WithHandler(jsonpatch.HandlerFuncs{}))
+		})
+		It("should use the functions", func() {
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithHandler(jsonpatch.HandlerFuncs{
+				ReplaceFunc: func(pointer jsonpatch.JSONPointer, modified, _ interface{}) []jsonpatch.JSONPatch {
+					return []jsonpatch.JSONPatch{{Operation: "add", Path: pointer.String(), Value: modified}}
+				},
+			}))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(ConsistOf(
+				jsonpatch.JSONPatch{Operation: "add", Path: "/c/str", Value: "new"},
+				jsonpatch.JSONPatch{Operation: "add", Path: "/c/strmap/a", Value: "1"},
+				jsonpatch.JSONPatch{Operation: "remove", Path: "/c/strmap/b"},
+			))
+		})
+	})
+	Context("ChainHandlers", func() {
+		It("should use the DefaultHandler without middlewares", func() {
+			testPatchWithExpected(modified, current, modified, jsonpatch.WithHandler(jsonpatch.ChainHandlers()))
+		})
+		It("should wrap the handlers in order", func() {
+			var logged []string
+			logging := func(next jsonpatch.Handler) jsonpatch.Handler {
+				return jsonpatch.HandlerFuncs{
+					AddFunc: func(pointer jsonpatch.JSONPointer, modified interface{}) []jsonpatch.JSONPatch {
+						patches := next.Add(pointer, modified)
+						for _, patch := range patches {
+							logged = append(logged, patch.Operation+" "+patch.Path)
+						}
+						return patches
+					},
+					RemoveFunc: func(pointer jsonpatch.JSONPointer, current interface{}) []jsonpatch.JSONPatch {
+						patches := next.Remove(pointer, current)
+						for _, patch := range patches {
+							logged = append(logged, patch.Operation+" "+patch.Path)
+						}
+						return patches
+					},
+					ReplaceFunc: func(pointer jsonpatch.JSONPointer, modified, current interface{}) []jsonpatch.JSONPatch {
+						patches := next.Replace(pointer, modified, current)
+						for _, patch := range patches {
+							logged = append(logged, patch.Operation+" "+patch.Path)
+						}
+						return patches
+					},
+				}
+			}
+			test := func(next jsonpatch.Handler) jsonpatch.Handler {
+				return jsonpatch.HandlerFuncs{
+					AddFunc: next.Add,
+					RemoveFunc: func(pointer jsonpatch.JSONPointer, current interface{}) []jsonpatch.JSONPatch {
+						return append([]jsonpatch.JSONPatch{{Operation: "test", Path: pointer.String(), Value: current}}, next.Remove(pointer, current)...)
+					},
+					ReplaceFunc: func(pointer jsonpatch.JSONPointer, modified, current interface{}) []jsonpatch.JSONPatch {
+						return append([]jsonpatch.JSONPatch{{Operation: "test", Path: pointer.String(), Value: current}}, next.Replace(pointer, modified, current)...)
+					},
+				}
+			}
+
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithHandler(jsonpatch.ChainHandlers(logging, test)))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(ConsistOf(
+				jsonpatch.JSONPatch{Operation: "test", Path: "/c/str", Value: "old"},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/c/str", Value: "new"},
+				jsonpatch.JSONPatch{Operation: "add", Path: "/c/strmap/a", Value: "1"},
+				jsonpatch.JSONPatch{Operation: "test", Path: "/c/strmap/b", Value: "2"},
+				jsonpatch.JSONPatch{Operation: "remove", Path: "/c/strmap/b"},
+			))
+			Ω(logged).Should(ConsistOf("test /c/str", "replace /c/str", "add /c/strmap/a", "test /c/strmap/b", "remove /c/strmap/b"))
+			testPatchWithExpected(modified, current, modified, jsonpatch.WithHandler(jsonpatch.ChainHandlers(logging, test)))
+		})
+	})
+})
@@ -0,0 +1,134 @@
+package jsonpatch
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ImmutableError is returned if a patch would change values which are immutable (see Immutable)
+type ImmutableError struct {
+	// Pointers are the paths of all immutable values which would change
+	Pointers []JSONPointer
+}
+
+// Error implements error
+func (e *ImmutableError) Error() string {
+	pointers := make([]string, len(e.Pointers))
+	for j, pointer := range e.Pointers {
+		pointers[j] = pointer.String()
+	}
+
+	return "immutable values would change at: " + strings.Join(pointers, ", ")
+}
+
+// violates records the pointer if it matches one of the immutable patterns and returns true in that case. If the value
+// at the pointer is patched as a whole, the immutable values under the pointer which would change are recorded instead.
+func (w *walker) violates(pointer JSONPointer, modified, current interface{}) bool {
+	if matchPaths(pointer, w.immutable) {
+		w.violations = append(w.violations, append(JSONPointer{}, pointer...))
+		return true
+	}
+	if !parentOfPaths(pointer, w.immutable) {
+		return false
+	}
+	changed := changedPaths(pointer, modified, current, w.immutable)
+	w.violations = append(w.violations, changed...)
+
+	return len(changed) > 0
+}
+
+// changedPaths returns the pointers of the values under the pointer which paths match one of the patterns and which
+// differ between the modified and the current value at the pointer
+func changedPaths(pointer JSONPointer, modified, current interface{}, patterns []string) []JSONPointer {
+	m, err := decode(modified)
+	if err != nil {
+		return []JSONPointer{append(JSONPointer{}, pointer...)}
+	}
+	c, err := decode(current)
+	if err != nil {
+		return []JSONPointer{append(JSONPointer{}, pointer...)}
+	}
+
+	var changed []JSONPointer
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		elements := ParseJSONPointer(pattern)
+		if len(elements) <= len(pointer) || !parentOfPaths(pointer, []string{pattern}) {
+			continue
+		}
+		// the values are compared relative to the pointer
+		for _, relative := range append(expand(m, JSONPointer{""}, elements[len(pointer):]), expand(c, JSONPointer{""}, elements[len(pointer):])...) {
+			if seen[relative.String()] {
+				continue
+			}
+			seen[relative.String()] = true
+
+			mValue, okM := lookup(m, relative)
+			cValue, okC := lookup(c, relative)
+			if okM != okC || !reflect.DeepEqual(mValue, cValue) {
+				changed = append(changed, append(append(JSONPointer{}, pointer...), relative[1:]...))
+			}
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		return changed[i].String() < changed[j].String()
+	})
+
+	return changed
+}
+
+// expand returns the pointers of the values of a generic JSON document which match the pattern elements, the pointer is
+// the pointer of the document itself
+func expand(doc interface{}, pointer JSONPointer, elements []string) []JSONPointer {
+	if len(elements) == 0 {
+		return []JSONPointer{pointer}
+	}
+
+	var pointers []JSONPointer
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		if elements[0] == wildcard {
+			for key, value := range v {
+				pointers = append(pointers, expand(value, slices.Clip(pointer).Add(key), elements[1:])...)
+			}
+		} else if value, ok := v[unescape(elements[0])]; ok {
+			pointers = append(pointers, expand(value, append(slices.Clip(pointer), elements[0]), elements[1:])...)
+		}
+	case []interface{}:
+		for j, value := range v {
+			if elements[0] == wildcard || elements[0] == strconv.Itoa(j) {
+				pointers = append(pointers, expand(value, slices.Clip(pointer).Add(strconv.Itoa(j)), elements[1:])...)
+			}
+		}
+	}
+
+	return pointers
+}
+
+// readOnly returns a Predicate which drops the patches of values which paths match one of the patterns and of parents
+// which would change them
+func readOnly(patterns []string) Predicate {
+	accept := func(pointer JSONPointer, modified, current interface{}) bool {
+		if matchPaths(pointer, patterns) {
+			return false
+		}
+		return !parentOfPaths(pointer, patterns) || len(changedPaths(pointer, modified, current, patterns)) == 0
+	}
+
+	return descendFuncs{Funcs: Funcs{
+		AddFunc: func(pointer JSONPointer, modified interface{}) bool {
+			return accept(pointer, modified, nil)
+		},
+		RemoveFunc: func(pointer JSONPointer, current interface{}) bool {
+			return accept(pointer, nil, current)
+		},
+		ReplaceFunc: func(pointer JSONPointer, modified, current interface{}) bool {
+			return accept(pointer, modified, current)
+		},
+	}, descendFunc: func(pointer JSONPointer, _, _ interface{}) bool {
+		return !matchPaths(pointer, patterns)
+	}}
+}
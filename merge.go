@@ -0,0 +1,270 @@
+package jsonpatch
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// MergeConflict describes an operation of a patch which was dropped while merging patches, because it conflicts with an
+// operation of a preceding patch (e.g. both change the same value in different ways) or with a dropped operation of the
+// same patch
+type MergeConflict struct {
+	// Pointer is the path of the dropped operation
+	Pointer JSONPointer
+
+	// Patch is the index of the patch which contains the dropped operation
+	Patch int
+
+	// Operation is the dropped operation
+	Operation JSONPatch
+
+	// Conflicting is the operation which the dropped operation conflicts with
+	Conflicting JSONPatch
+}
+
+// MergeResult is the result of merging patches
+type MergeResult struct {
+	// Patch is the combined JSONPatch which is applied to the base JSON
+	Patch JSONPatchList
+
+	// Conflicts lists all operations which were dropped due to conflicts
+	Conflicts []MergeConflict
+}
+
+// MergePatches merges patches which were created concurrently against the same base JSON into a single JSONPatch. The
+// operations of each patch are rebased on the operations of all preceding patches: array indices are shifted by the
+// elements which were added or removed by preceding patches, and operations which change a value that was changed by
+// a preceding patch are dropped and reported as conflicts (i.e. the first patch wins), as well as operations which depend
+// on a dropped operation. Operations which are identical to an operation of a preceding patch are dropped without conflict.
+func MergePatches(base interface{}, patches ...JSONPatchList) (MergeResult, error) {
+	doc, err := decode(base)
+	if err != nil {
+		return MergeResult{}, err
+	}
+
+	var (
+		merged    []operation
+		conflicts []MergeConflict
+	)
+	for n, patch := range patches {
+		// current is the base JSON patched with all operations of the patch processed so far, it is used to inspect the
+		// operations in their original context
+		current := clone(doc)
+		// others are the merged operations of the preceding patches, they are rebased on the accepted operations
+		others := append([]operation{}, merged...)
+		// excluded are the inverse operations of the dropped operations which are used to rebase the remaining operations
+		var excluded []operation
+		for _, p := range patch.List() {
+			op, err := newOperation(current, p)
+			if err != nil {
+				return MergeResult{}, err
+			}
+			if current, err = applyPatch(current, op.patch); err != nil {
+				return MergeResult{}, err
+			}
+
+			if conflicting := dropped(op, excluded, others); conflicting != nil {
+				if conflicting.conflict {
+					conflicts = append(conflicts, MergeConflict{
+						Pointer:     ParseJSONPointer(p.Path),
+						Patch:       n,
+						Operation:   p,
+						Conflicting: conflicting.origin,
+					})
+				}
+				excluded = append([]operation{op.inverse()}, excluded...)
+				continue
+			}
+			merged = append(merged, transformAll(transformAll(op, excluded), others))
+		}
+	}
+
+	result, err := newJSONPatchListFromOperations(merged)
+
+	return MergeResult{Patch: result, Conflicts: conflicts}, err
+}
+
+// operation is a JSONPatch operation which can be rebased on other operations
+type operation struct {
+	patch   JSONPatch
+	origin  JSONPatch
+	pointer JSONPointer
+
+	// element is true if the operation targets an element of an array
+	element bool
+	// insert is true if the operation adds a new element to an array or a new member to an object
+	insert bool
+}
+
+// newJSONPatchListFromOperations creates a JSONPatchList from a sequence of operations
+func newJSONPatchListFromOperations(ops []operation) (JSONPatchList, error) {
+	list := make([]JSONPatch, len(ops))
+	for j, op := range ops {
+		list[j] = op.patch
+	}
+
+	return NewJSONPatchList(list)
+}
+
+// newOperation creates an operation from a JSONPatch and inspects its target in the generic JSON document, the index
+// "-" which refers to the end of an array is replaced by the actual index
+func newOperation(doc interface{}, patch JSONPatch) (operation, error) {
+	op := operation{
+		patch:   patch,
+		origin:  patch,
+		pointer: ParseJSONPointer(patch.Path),
+	}
+	if op.pointer[0] != "" {
+		return op, fmt.Errorf("invalid pointer at: %s", patch.Path)
+	}
+
+	if len(op.pointer) > 1 {
+		parent, _ := lookup(doc, op.pointer[:len(op.pointer)-1])
+		switch v := parent.(type) {
+		case []interface{}:
+			op.element = true
+			op.insert = patch.Operation == "add"
+			if op.insert && op.pointer[len(op.pointer)-1] == "-" {
+				op = op.withIndex(len(op.pointer)-1, len(v))
+			}
+		case map[string]interface{}:
+			_, ok := v[unescape(op.pointer[len(op.pointer)-1])]
+			op.insert = patch.Operation == "add" && !ok
+		}
+	}
+
+	return op, nil
+}
+
+// removal returns true if the operation removes a value
+func (op operation) removal() bool {
+	return op.patch.Operation == "remove"
+}
+
+// supported returns true if the operation is an 'add', 'replace' or 'remove' operation
+func (op operation) supported() bool {
+	return op.patch.Operation == "add" || op.patch.Operation == "replace" || op.patch.Operation == "remove"
+}
+
+// structural returns true if the operation adds or removes an element of an array and shifts the indices of the others
+func (op operation) structural() bool {
+	return op.element && (op.insert || op.removal())
+}
+
+// withIndex returns a copy of the operation with a new array index at the position of the pointer
+func (op operation) withIndex(position, index int) operation {
+	op.pointer = append(JSONPointer{}, op.pointer...)
+	op.pointer[position] = strconv.Itoa(index)
+	op.patch.Path = op.pointer.String()
+
+	return op
+}
+
+// inverse returns an operation which reverts the structural changes of the operation, the values are not reverted
+func (op operation) inverse() operation {
+	inverse := op
+	switch {
+	case op.insert:
+		inverse.patch = JSONPatch{Operation: "remove", Path: op.patch.Path}
+		inverse.insert = false
+	case op.removal():
+		inverse.patch = JSONPatch{Operation: "add", Path: op.patch.Path}
+		inverse.insert = true
+	default:
+		inverse.patch = JSONPatch{Operation: "replace", Path: op.patch.Path}
+	}
+
+	return inverse
+}
+
+// conflicting is the result of a transformation which drops an operation
+type conflicting struct {
+	origin   JSONPatch
+	conflict bool
+}
+
+// dropped transforms the operation over sequences of operations which take precedence and returns the conflicting
+// operation if it is dropped
+func dropped(op operation, sequences ...[]operation) *conflicting {
+	for _, ops := range sequences {
+		for _, other := range ops {
+			var ok, conflict bool
+			if op, ok, conflict = transform(op, other, true); !ok {
+				return &conflicting{origin: other.origin, conflict: conflict}
+			}
+		}
+	}
+
+	return nil
+}
+
+// transformAll transforms the operation over a sequence of operations which take precedence, and the operations (in
+// place) over the operation
+func transformAll(op operation, ops []operation) operation {
+	for j, other := range ops {
+		ops[j], _, _ = transform(other, op, false)
+		op, _, _ = transform(op, other, true)
+	}
+
+	return op
+}
+
+// transform transforms the operation a over the operation b which was applied before. If both insert an element at the
+// same index, the element of b is inserted first if b takes precedence. It returns false if a is dropped, either because
+// it conflicts with b or because it is a duplicate of b.
+func transform(a, b operation, precedence bool) (operation, bool, bool) {
+	if position := len(b.pointer) - 1; b.structural() && len(a.pointer) > position && hasPrefix(a.pointer, b.pointer[:position]) {
+		// shift the index of a by the element which was added or removed by b
+		i, _ := strconv.Atoi(b.pointer[position])
+		j, err := strconv.Atoi(a.pointer[position])
+		if err != nil {
+			return a, true, false
+		}
+
+		target := len(a.pointer) == position+1
+		switch {
+		case b.insert && (j > i || j == i && (precedence || !(target && a.insert))):
+			return a.withIndex(position, j+1), true, false
+		case b.removal() && j > i:
+			return a.withIndex(position, j-1), true, false
+		case b.removal() && j == i && target && a.insert:
+			return a, true, false
+		case b.removal() && j == i:
+			return a, false, !(target && a.removal())
+		}
+
+		return a, true, false
+	}
+
+	switch {
+	case len(a.pointer) == len(b.pointer) && hasPrefix(a.pointer, b.pointer):
+		return a, false, a.patch.Operation != b.patch.Operation || !equal(a.patch.Value, b.patch.Value)
+	case hasPrefix(a.pointer, b.pointer) || hasPrefix(b.pointer, a.pointer):
+		return a, false, true
+	}
+
+	return a, true, false
+}
+
+// hasPrefix returns true if the pointer starts with the prefix
+func hasPrefix(pointer, prefix JSONPointer) bool {
+	if len(pointer) < len(prefix) {
+		return false
+	}
+	for j := range prefix {
+		if pointer[j] != prefix[j] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// equal returns true if both values are encoded to the same JSON
+func equal(a, b interface{}) bool {
+	x, errX := decode(a)
+	y, errY := decode(b)
+
+	return errX == nil && errY == nil && reflect.DeepEqual(x, y)
+}
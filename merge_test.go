@@ -0,0 +1,210 @@
+package jsonpatch_test
+
+import (
+	"encoding/json"
+	"strconv"
+
+	jsonpatch2 "github.com/evanphx/json-patch/v5"
+	"github.com/go-faker/faker/v4"
+	"github.com/go-faker/faker/v4/pkg/options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+var _ = Describe("MergePatches", func() {
+	var base C
+	BeforeEach(func() {
+		base = C{
+			Str:    "base",
+			StrMap: map[string]string{"a": "1"},
+			IntMap: map[string]int{"a": 1},
+			StructMap: map[string]B{
+				"a": {Str: "a", Int: 1},
+			},
+		}
+	})
+
+	Context("MergePatches", func() {
+		It("should merge patches created against the same base", func() {
+			modified := base
+			modified.Str = "modified"
+			first, err := jsonpatch.CreateJSONPatch(modified, base)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			modified = base
+			modified.IntMap = map[string]int{"a": 2, "b": 3}
+			second, err := jsonpatch.CreateJSONPatch(modified, base)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			result, err := jsonpatch.MergePatches(base, first, second)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(result.Conflicts).Should(BeEmpty())
+
+			modified.Str = "modified"
+			testMergePatchesWithExpected(base, result, modified)
+		})
+		It("should shift the indices of added elements", func() {
+			result := testMergePatches([]string{"x", "y"}, []string{"z", "x", "first", "second", "y", "third"},
+				[]jsonpatch.JSONPatch{{Operation: "add", Path: "/1", Value: "first"}},
+				[]jsonpatch.JSONPatch{{Operation: "add", Path: "/1", Value: "second"}, {Operation: "add", Path: "/-", Value: "third"}},
+				[]jsonpatch.JSONPatch{{Operation: "add", Path: "/0", Value: "z"}},
+			)
+			Ω(result.Patch.List()).Should(Equal([]jsonpatch.JSONPatch{
+				{Operation: "add", Path: "/1", Value: "first"},
+				{Operation: "add", Path: "/2", Value: "second"},
+				{Operation: "add", Path: "/4", Value: "third"},
+				{Operation: "add", Path: "/0", Value: "z"},
+			}))
+		})
+		It("should shift the indices of removed elements", func() {
+			result := testMergePatches([]string{"a", "b", "c", "d"}, []string{"b", "C", "D"},
+				[]jsonpatch.JSONPatch{{Operation: "remove", Path: "/0"}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/2", Value: "C"}, {Operation: "replace", Path: "/3", Value: "D"}},
+			)
+			Ω(result.Patch.List()).Should(Equal([]jsonpatch.JSONPatch{
+				{Operation: "remove", Path: "/0"},
+				{Operation: "replace", Path: "/1", Value: "C"},
+				{Operation: "replace", Path: "/2", Value: "D"},
+			}))
+		})
+		It("should drop duplicated operations", func() {
+			result := testMergePatches([]string{"a", "b", "c"}, []string{"a", "B"},
+				[]jsonpatch.JSONPatch{{Operation: "remove", Path: "/2"}, {Operation: "replace", Path: "/1", Value: "B"}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/1", Value: "B"}, {Operation: "remove", Path: "/2"}},
+			)
+			Ω(result.Conflicts).Should(BeEmpty())
+			Ω(result.Patch.Len()).Should(Equal(2))
+		})
+		It("should report conflicts", func() {
+			result := testMergePatches(base, C{Str: "first", StrMap: base.StrMap, IntMap: base.IntMap, StructMap: base.StructMap},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/str", Value: "first"}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/str", Value: "second"}},
+			)
+			Ω(result.Conflicts).Should(Equal([]jsonpatch.MergeConflict{
+				{
+					Pointer:     jsonpatch.ParseJSONPointer("/str"),
+					Patch:       1,
+					Operation:   jsonpatch.JSONPatch{Operation: "replace", Path: "/str", Value: "second"},
+					Conflicting: jsonpatch.JSONPatch{Operation: "replace", Path: "/str", Value: "first"},
+				},
+			}))
+		})
+		It("should report conflicts of nested values", func() {
+			result := testMergePatches([]B{{Str: "a"}, {Str: "b"}, {Str: "c"}}, []B{{Str: "a"}, {Str: "c", Int: 2}},
+				[]jsonpatch.JSONPatch{{Operation: "remove", Path: "/1"}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/1/int", Value: 1}, {Operation: "replace", Path: "/2/int", Value: 2}},
+			)
+			Ω(result.Conflicts).Should(HaveLen(1))
+			Ω(result.Conflicts[0].Pointer.String()).Should(Equal("/1/int"))
+			Ω(result.Conflicts[0].Conflicting).Should(Equal(jsonpatch.JSONPatch{Operation: "remove", Path: "/1"}))
+
+			result = testMergePatches(base, C{Str: "base", StrMap: base.StrMap, IntMap: base.IntMap, StructMap: map[string]B{}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/structmap", Value: map[string]B{}}},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/structmap/a/int", Value: 2}},
+			)
+			Ω(result.Conflicts).Should(HaveLen(1))
+		})
+		It("should drop operations which depend on dropped operations", func() {
+			result := testMergePatches(base, C{Str: "base", StrMap: map[string]string{"a": "1", "b": "1", "c": "3"}, IntMap: base.IntMap, StructMap: map[string]B{"a": {Str: "a", Int: 1}, "b": {Str: "first"}}},
+				[]jsonpatch.JSONPatch{{Operation: "add", Path: "/structmap/b", Value: B{Str: "first"}}, {Operation: "add", Path: "/strmap/b", Value: "1"}},
+				[]jsonpatch.JSONPatch{
+					{Operation: "add", Path: "/structmap/b", Value: B{Str: "second"}},
+					{Operation: "replace", Path: "/structmap/b/int", Value: 2},
+					{Operation: "add", Path: "/strmap/c", Value: "3"},
+				},
+			)
+			Ω(result.Conflicts).Should(HaveLen(2))
+			Ω(result.Conflicts[1].Operation).Should(Equal(jsonpatch.JSONPatch{Operation: "replace", Path: "/structmap/b/int", Value: 2}))
+			Ω(result.Conflicts[1].Conflicting).Should(Equal(jsonpatch.JSONPatch{Operation: "add", Path: "/structmap/b", Value: B{Str: "second"}}))
+		})
+		It("should rebase operations on dropped operations of the same patch", func() {
+			result := testMergePatches([]string{"a", "b", "c"}, []string{"first", "second", "c", "third"},
+				[]jsonpatch.JSONPatch{{Operation: "replace", Path: "/0", Value: "first"}},
+				[]jsonpatch.JSONPatch{
+					{Operation: "remove", Path: "/0"},
+					{Operation: "replace", Path: "/0", Value: "second"},
+					{Operation: "add", Path: "/-", Value: "third"},
+				},
+			)
+			Ω(result.Patch.List()).Should(Equal([]jsonpatch.JSONPatch{
+				{Operation: "replace", Path: "/0", Value: "first"},
+				{Operation: "replace", Path: "/1", Value: "second"},
+				{Operation: "add", Path: "/3", Value: "third"},
+			}))
+			Ω(result.Conflicts).Should(HaveLen(1))
+		})
+		It("should fail for patches which can't be applied to the base", func() {
+			list, err := jsonpatch.NewJSONPatchList([]jsonpatch.JSONPatch{{Operation: "remove", Path: "/missing"}})
+			Ω(err).ShouldNot(HaveOccurred())
+			_, err = jsonpatch.MergePatches(base, list)
+			Ω(err).Should(HaveOccurred())
+
+			list, err = jsonpatch.NewJSONPatchList([]jsonpatch.JSONPatch{{Operation: "move", Path: "/str"}})
+			Ω(err).ShouldNot(HaveOccurred())
+			_, err = jsonpatch.MergePatches(base, list)
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+	Context("MergePatches_fuzzy", func() {
+		for i := 0; i < 100; i++ {
+			It("fuzzy "+strconv.Itoa(i), func() {
+				base := G{}
+				Ω(faker.FakeData(&base, options.WithRandomMapAndSliceMaxSize(5))).Should(Succeed())
+
+				var lists []jsonpatch.JSONPatchList
+				for j := 0; j < 3; j++ {
+					modified := G{}
+					Ω(faker.FakeData(&modified, options.WithRandomMapAndSliceMaxSize(5))).Should(Succeed())
+					list, err := jsonpatch.CreateJSONPatch(modified, base)
+					Ω(err).ShouldNot(HaveOccurred())
+					lists = append(lists, list)
+				}
+
+				result, err := jsonpatch.MergePatches(base, lists...)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				// the first patch is never dropped and the merged patch must be applicable to the base JSON
+				Ω(result.Patch.List()[:lists[0].Len()]).Should(Equal(lists[0].List()))
+				baseJSON, err := json.Marshal(base)
+				Ω(err).ShouldNot(HaveOccurred())
+				jsonPatch, err := jsonpatch2.DecodePatch(result.Patch.Raw())
+				Ω(err).ShouldNot(HaveOccurred())
+				_, err = jsonPatch.Apply(baseJSON)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+		}
+	})
+})
+
+func testMergePatches(base, expected interface{}, patches ...[]jsonpatch.JSONPatch) jsonpatch.MergeResult {
+	var lists []jsonpatch.JSONPatchList
+	for _, patch := range patches {
+		list, err := jsonpatch.NewJSONPatchList(patch)
+		Ω(err).ShouldNot(HaveOccurred())
+		lists = append(lists, list)
+	}
+
+	result, err := jsonpatch.MergePatches(base, lists...)
+	Ω(err).ShouldNot(HaveOccurred())
+	testMergePatchesWithExpected(base, result, expected)
+
+	return result
+}
+
+func testMergePatchesWithExpected(base interface{}, result jsonpatch.MergeResult, expected interface{}) {
+	baseJSON, err := json.Marshal(base)
+	Ω(err).ShouldNot(HaveOccurred())
+	expectedJSON, err := json.Marshal(expected)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	patchedJSON := baseJSON
+	if !result.Patch.Empty() {
+		jsonPatch, err := jsonpatch2.DecodePatch(result.Patch.Raw())
+		Ω(err).ShouldNot(HaveOccurred())
+		patchedJSON, err = jsonPatch.Apply(baseJSON)
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+	Ω(patchedJSON).Should(MatchJSON(expectedJSON))
+}
@@ -0,0 +1,147 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+)
+
+// Normalize removes redundant operations from the JSONPatchList without applying it to a document (e.g. operations of a
+// Handler which returns multiple operations): operations on the same value are collapsed into a single operation, a
+// 'remove' of a value which is added again is converted into a 'replace', values which are added and removed again
+// cancel out and operations which are shadowed by a later operation on a parent value are dropped.
+func (l JSONPatchList) Normalize() (JSONPatchList, error) {
+	return Compose(l, JSONPatchList{})
+}
+
+// NormalizeWithDocument normalizes the JSONPatchList like Normalize and additionally inspects the document to which the
+// JSONPatchList is applied: operations which don't change the document (e.g. a 'replace' with an identical value) are
+// dropped and the operations on the values of an object or array are collapsed into a single 'replace' of the object or
+// array if it is smaller in encoded size.
+func (l JSONPatchList) NormalizeWithDocument(document interface{}) (JSONPatchList, error) {
+	normalized, err := l.Normalize()
+	if err != nil {
+		return JSONPatchList{}, err
+	}
+
+	doc, err := decode(document)
+	if err != nil {
+		return JSONPatchList{}, err
+	}
+
+	// drop all operations which don't change the document
+	var ops []operation
+	patched := clone(doc)
+	for _, patch := range normalized.List() {
+		op, err := newOperation(patched, patch)
+		if err != nil {
+			return JSONPatchList{}, err
+		}
+		if !op.supported() {
+			// the document can't be inspected beyond operations which aren't supported
+			return normalized, nil
+		}
+		if !op.insert || !op.element {
+			if value, ok := lookup(patched, op.pointer); ok && op.patch.Operation != "remove" && equal(value, op.patch.Value) {
+				continue
+			}
+		}
+		if patched, err = applyPatch(patched, patch); err != nil {
+			return JSONPatchList{}, err
+		}
+		ops = append(ops, op)
+	}
+
+	if ops, err = collapse(doc, patched, JSONPointer{""}, ops); err != nil {
+		return JSONPatchList{}, err
+	}
+
+	return newJSONPatchListFromOperations(ops)
+}
+
+// collapse returns the operations on the values within the prefix or a single 'replace' of the value at the prefix,
+// whichever is smaller in encoded size. The operations of the children are collapsed first, unless elements are added
+// to or removed from the value at the prefix which shifts the pointers of the children.
+func collapse(original, patched interface{}, prefix JSONPointer, ops []operation) ([]operation, error) {
+	position := len(prefix)
+	for _, op := range ops {
+		if len(op.pointer) <= position {
+			return ops, nil
+		}
+	}
+
+	if !shifted(ops, position) {
+		// group the operations by the child they change, in the order of their first operation
+		var tokens []string
+		groups := map[string][]int{}
+		for j, op := range ops {
+			token := op.pointer[position]
+			if _, ok := groups[token]; !ok {
+				tokens = append(tokens, token)
+			}
+			groups[token] = append(groups[token], j)
+		}
+
+		var collapsed []operation
+		for _, token := range tokens {
+			group := make([]operation, len(groups[token]))
+			for j, k := range groups[token] {
+				group[j] = ops[k]
+			}
+			children, err := collapse(original, patched, append(append(JSONPointer{}, prefix...), token), group)
+			if err != nil {
+				return nil, err
+			}
+			collapsed = append(collapsed, children...)
+		}
+		if len(collapsed) < len(ops) {
+			// a collapsed child is moved to the position of its first operation, children are independent of each other
+			ops = collapsed
+		}
+	}
+
+	// the root value is never replaced
+	if len(ops) < 2 || position < 2 {
+		return ops, nil
+	}
+	if _, ok := lookup(original, prefix); !ok {
+		return ops, nil
+	}
+	value, ok := lookup(patched, prefix)
+	if !ok || value == nil {
+		return ops, nil
+	}
+
+	replace := operation{patch: JSONPatch{Operation: "replace", Path: prefix.String(), Value: value}, pointer: prefix}
+	replace.origin = replace.patch
+	if ok, err := smaller([]operation{replace}, ops); err != nil || !ok {
+		return ops, err
+	}
+
+	return []operation{replace}, nil
+}
+
+// shifted returns true if an operation adds or removes an element at the position of the pointers
+func shifted(ops []operation, position int) bool {
+	for _, op := range ops {
+		if len(op.pointer) == position+1 && op.structural() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// smaller returns true if the encoded size of the operations a is smaller than the size of the operations b
+func smaller(a, b []operation) (bool, error) {
+	var size [2]int
+	for j, ops := range [][]operation{a, b} {
+		for _, op := range ops {
+			raw, err := json.Marshal(op.patch)
+			if err != nil {
+				return false, err
+			}
+			size[j] += len(raw) + 1
+		}
+	}
+
+	return size[0] < size[1], nil
+}
@@ -0,0 +1,175 @@
+package jsonpatch_test
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/go-faker/faker/v4/pkg/options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+var _ = Describe("Normalize", func() {
+	Context("Normalize", func() {
+		It("should remove redundant operations", func() {
+			testNormalize(C{Str: "a", StrMap: map[string]string{"a": "1"}},
+				[]jsonpatch.JSONPatch{
+					{Operation: "remove", Path: "/str"},
+					{Operation: "add", Path: "/str", Value: "b"},
+					{Operation: "replace", Path: "/strmap/a", Value: "2"},
+					{Operation: "replace", Path: "/strmap/a", Value: "3"},
+				},
+				[]jsonpatch.JSONPatch{
+					{Operation: "replace", Path: "/str", Value: "b"},
+					{Operation: "replace", Path: "/strmap/a", Value: "3"},
+				},
+			)
+		})
+		It("should drop operations shadowed by a parent operation", func() {
+			testNormalize(C{StrMap: map[string]string{"a": "1"}},
+				[]jsonpatch.JSONPatch{
+					{Operation: "replace", Path: "/strmap/a", Value: "2"},
+					{Operation: "add", Path: "/strmap/b", Value: "3"},
+					{Operation: "replace", Path: "/strmap", Value: map[string]string{"c": "4"}},
+				},
+				[]jsonpatch.JSONPatch{
+					{Operation: "replace", Path: "/strmap", Value: map[string]string{"c": "4"}},
+				},
+			)
+		})
+		It("should keep unsupported operations in their order", func() {
+			testNormalize(C{Str: "a"},
+				[]jsonpatch.JSONPatch{
+					{Operation: "test", Path: "/str", Value: "a"},
+					{Operation: "replace", Path: "/str", Value: "b"},
+					{Operation: "test", Path: "/str", Value: "b"},
+					{Operation: "replace", Path: "/str", Value: "c"},
+				},
+				[]jsonpatch.JSONPatch{
+					{Operation: "test", Path: "/str", Value: "a"},
+					{Operation: "replace", Path: "/str", Value: "b"},
+					{Operation: "test", Path: "/str", Value: "b"},
+					{Operation: "replace", Path: "/str", Value: "c"},
+				},
+			)
+		})
+	})
+	Context("NormalizeWithDocument", func() {
+		It("should drop operations which don't change the document", func() {
+			testNormalizeWithDocument(C{Str: "a", StrMap: map[string]string{"a": "1"}},
+				[]jsonpatch.JSONPatch{
+					{Operation: "replace", Path: "/str", Value: "a"},
+					{Operation: "add", Path: "/strmap/a", Value: "1"},
+					{Operation: "add", Path: "/strmap/b", Value: "2"},
+				},
+				[]jsonpatch.JSONPatch{
+					{Operation: "add", Path: "/strmap/b", Value: "2"},
+				},
+			)
+		})
+		It("should collapse operations into a parent replace if it is smaller", func() {
+			testNormalizeWithDocument(C{Str: "a", StrMap: map[string]string{"a": "1", "b": "2", "c": "3"}},
+				[]jsonpatch.JSONPatch{
+					{Operation: "replace", Path: "/strmap/a", Value: "4"},
+					{Operation: "replace", Path: "/str", Value: "b"},
+					{Operation: "replace", Path: "/strmap/b", Value: "5"},
+					{Operation: "remove", Path: "/strmap/c"},
+				},
+				[]jsonpatch.JSONPatch{
+					{Operation: "replace", Path: "/strmap", Value: map[string]string{"a": "4", "b": "5"}},
+					{Operation: "replace", Path: "/str", Value: "b"},
+				},
+			)
+			testNormalizeWithDocument([]B{{Str: "a"}, {Str: "b"}},
+				[]jsonpatch.JSONPatch{
+					{Operation: "replace", Path: "/0/int", Value: 1},
+					{Operation: "replace", Path: "/1/int", Value: 2},
+				},
+				[]jsonpatch.JSONPatch{
+					{Operation: "replace", Path: "/0/int", Value: 1},
+					{Operation: "replace", Path: "/1/int", Value: 2},
+				},
+			)
+		})
+		It("should not collapse operations of elements which are shifted", func() {
+			testNormalizeWithDocument(D{StringSlice: []string{"a", "b", "c"}},
+				[]jsonpatch.JSONPatch{
+					{Operation: "remove", Path: "/strs/0"},
+					{Operation: "replace", Path: "/strs/0", Value: "d"},
+					{Operation: "replace", Path: "/strs/1", Value: "e"},
+				},
+				[]jsonpatch.JSONPatch{
+					{Operation: "replace", Path: "/strs", Value: []string{"d", "e"}},
+				},
+			)
+			testNormalizeWithDocument([]C{{StrMap: map[string]string{"a": "1", "b": "2"}}, {}},
+				[]jsonpatch.JSONPatch{
+					{Operation: "remove", Path: "/0"},
+					{Operation: "add", Path: "/0/strmap", Value: map[string]string{"c": "3"}},
+				},
+				[]jsonpatch.JSONPatch{
+					{Operation: "remove", Path: "/0"},
+					{Operation: "add", Path: "/0/strmap", Value: map[string]string{"c": "3"}},
+				},
+			)
+		})
+		It("should fail for patches which can't be applied to the document", func() {
+			list, err := jsonpatch.NewJSONPatchList([]jsonpatch.JSONPatch{{Operation: "remove", Path: "/missing"}})
+			Ω(err).ShouldNot(HaveOccurred())
+			_, err = list.NormalizeWithDocument(C{})
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+	Context("NormalizeWithDocument_fuzzy", func() {
+		for i := 0; i < 100; i++ {
+			It("fuzzy "+strconv.Itoa(i), func() {
+				var documents [2]G
+				for j := range documents {
+					Ω(faker.FakeData(&documents[j], options.WithRandomMapAndSliceMaxSize(5))).Should(Succeed())
+				}
+
+				list, err := jsonpatch.CreateJSONPatch(documents[1], documents[0])
+				Ω(err).ShouldNot(HaveOccurred())
+				normalized, err := list.NormalizeWithDocument(documents[0])
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(len(normalized.Raw())).Should(BeNumerically("<=", len(list.Raw())))
+
+				documentJSON, err := json.Marshal(documents[0])
+				Ω(err).ShouldNot(HaveOccurred())
+				expectedJSON, err := json.Marshal(documents[1])
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(testApplyPatches(documentJSON, normalized)).Should(MatchJSON(expectedJSON))
+			})
+		}
+	})
+})
+
+func testNormalize(document interface{}, patch, expected []jsonpatch.JSONPatch) {
+	list, err := jsonpatch.NewJSONPatchList(patch)
+	Ω(err).ShouldNot(HaveOccurred())
+	normalized, err := list.Normalize()
+	Ω(err).ShouldNot(HaveOccurred())
+	testNormalizeResult(document, list, normalized, expected)
+}
+
+func testNormalizeWithDocument(document interface{}, patch, expected []jsonpatch.JSONPatch) {
+	list, err := jsonpatch.NewJSONPatchList(patch)
+	Ω(err).ShouldNot(HaveOccurred())
+	normalized, err := list.NormalizeWithDocument(document)
+	Ω(err).ShouldNot(HaveOccurred())
+	testNormalizeResult(document, list, normalized, expected)
+}
+
+func testNormalizeResult(document interface{}, list, normalized jsonpatch.JSONPatchList, expected []jsonpatch.JSONPatch) {
+	expectedJSON, err := json.Marshal(expected)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(normalized.Raw()).Should(MatchJSON(expectedJSON))
+
+	// the normalized patch must be equivalent to the patch
+	documentJSON, err := json.Marshal(document)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(testApplyPatches(documentJSON, normalized)).Should(MatchJSON(testApplyPatches(documentJSON, list)))
+}
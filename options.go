@@ -3,20 +3,150 @@ package jsonpatch
 // Option allow to configure the walker instance
 type Option func(r *walker)
 
-// WithPredicate set a patch Predicate for the walker. This can be used to filter or validate the patch creation
+// WithPredicate set a patch Predicate for the walker. This can be used to filter or validate the patch creation.
+// Multiple predicates are combined with And. A ContextPredicate is bound to the context of the patch creation.
 func WithPredicate(predicate Predicate) Option {
 	return func(w *walker) {
-		w.predicate = predicate
+		if p, ok := predicate.(ContextPredicate); ok {
+			w.addPredicate(p.WithContext(w.ctx))
+			return
+		}
+		w.addPredicate(predicate)
+	}
+}
+
+// WithDecider set a Decider for the walker. In addition to the Predicate, it decides about every struct, slice and map
+// value before the walker walks into it and about every patch. The context of the patch creation is part of the
+// DecisionContext.
+func WithDecider(decider Decider) Option {
+	return func(w *walker) {
+		w.decider = decider
 	}
 }
 
-// WithHandler set a patch Handler for the walker. This can be used to customize the patch creation
+// WithErrorPredicate set a patch ErrorPredicate for the walker. This can be used to validate the patch creation and to
+// abort it with an error. It is combined with other predicates with And.
+func WithErrorPredicate(predicate ErrorPredicate) Option {
+	return func(w *walker) {
+		w.addPredicate(&errorPredicate{predicate: predicate, walker: w})
+	}
+}
+
+// WithErrorDecider set an ErrorDecider for the walker. This can be used like WithDecider and to abort the patch creation
+// with an error.
+func WithErrorDecider(decider ErrorDecider) Option {
+	return func(w *walker) {
+		w.decider = &errorDecider{decider: decider, walker: w}
+	}
+}
+
+// WithErrorHandler set a patch ErrorHandler for the walker. This can be used to customize the patch creation and to
+// abort it with an error
+func WithErrorHandler(handler ErrorHandler) Option {
+	return func(w *walker) {
+		w.handler = &errorHandler{handler: handler, walker: w}
+	}
+}
+
+// WithCollectErrors collects all errors of predicates, handlers and deciders instead of aborting the patch creation
+// after the first error. The errors are joined.
+func WithCollectErrors() Option {
+	return func(w *walker) {
+		w.collectErrors = true
+	}
+}
+
+// WithHandler set a patch Handler for the walker. This can be used to customize the patch creation. A ContextHandler is
+// bound to the context of the patch creation.
 func WithHandler(handler Handler) Option {
 	return func(w *walker) {
+		if h, ok := handler.(ContextHandler); ok {
+			w.handler = h.WithContext(w.ctx)
+			return
+		}
 		w.handler = handler
 	}
 }
 
+// WithConflictResolver set a ConflictResolver for the walker. This is used to resolve conflicts of three-way patches
+func WithConflictResolver(resolver ConflictResolver) Option {
+	return func(w *walker) {
+		w.resolver = resolver
+	}
+}
+
+// WithCollapseThreshold set a ratio for the walker which is used to collapse the patches of a struct, slice or map
+// value into a single replace of the value, if the encoded size of the patches exceeds the size of the replace by the
+// ratio (e.g. a ratio of 1 picks the smaller one). The size of the replace is estimated by its default encoding and
+// values which contain immutable values are never collapsed. This is not supported for three-way patches.
+func WithCollapseThreshold(ratio float64) Option {
+	return func(w *walker) {
+		w.collapseRatio = ratio
+	}
+}
+
+// WithMaxDepth set a max depth for the walker. Struct, pointer, slice and map values at the max depth (relative to the
+// prefix) are not walked any further and are patched as a whole if they differ, e.g. with a max depth of 1 only the
+// top-level values are replaced.
+func WithMaxDepth(depth int) Option {
+	return func(w *walker) {
+		w.maxDepth = depth
+	}
+}
+
+// WithAtomic set JSONPointer patterns for the walker. Struct, pointer, slice and map values which paths match one of
+// the patterns (e.g. "/spec/template" or "/data/*") are not walked any further and are patched as a whole if they differ.
+func WithAtomic(patterns ...string) Option {
+	return func(w *walker) {
+		w.atomic = append(w.atomic, patterns...)
+	}
+}
+
+// Immutable set JSONPointer patterns of immutable values for the walker. The patch creation fails with an
+// ImmutableError listing every value which path matches one of the patterns (e.g. "/metadata/uid" or "/spec/selector")
+// or which is a child of them, if it would change.
+func Immutable(patterns ...string) Option {
+	return func(w *walker) {
+		w.immutable = append(w.immutable, patterns...)
+	}
+}
+
+// ReadOnly drops the patches of values which paths match one of the patterns (e.g. "/status") or which are children of
+// them, without failing the patch creation (see ExcludePaths). Patches of parents which are patched as a whole are
+// dropped as well, if they would change one of the values.
+func ReadOnly(patterns ...string) Option {
+	return func(w *walker) {
+		w.addPredicate(readOnly(patterns))
+	}
+}
+
+// WithRedactor set a Redactor for the walker which passes a redacted copy of every patch to the sink, e.g. in order to log
+// it. The patches are passed in the order of the patch (or as soon as they are emitted, see WalkPatch) and an error of
+// the sink aborts the patch creation. Nothing is redacted with a nil sink.
+func WithRedactor(redactor Redactor, sink func(redacted JSONPatch) error) Option {
+	return func(w *walker) {
+		w.redactor, w.redactedSink = &redactor, sink
+	}
+}
+
+// WithHashFunc set a HashFunc for the walker. Struct, pointer, slice and map values with the same precomputed hash are
+// treated as unchanged and are not walked.
+func WithHashFunc(hash HashFunc) Option {
+	return func(w *walker) {
+		w.hash = hash
+	}
+}
+
+// WithConcurrency set the max number of goroutines of the walker which walk the children of large maps and slices in
+// parallel. The patches are merged in a deterministic order, i.e. the patches of the map entries are sorted by their
+// keys, as are the errors. Predicates, handlers and deciders must be safe for concurrent use, a ContextPredicate or
+// ContextHandler is bound to the context for every goroutine.
+func WithConcurrency(n int) Option {
+	return func(w *walker) {
+		w.concurrency = n
+	}
+}
+
 // WithPrefix is used to specify a prefix if only a sub part of JSON structure needs to be patched
 func WithPrefix(prefix []string) Option {
 	return func(w *walker) {
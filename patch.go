@@ -1,6 +1,7 @@
 package jsonpatch
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"slices"
@@ -46,70 +47,80 @@ func (l JSONPatchList) List() []JSONPatch {
 
 // CreateJSONPatch compares two JSON data structures and creates a JSONPatch according to RFC 6902
 func CreateJSONPatch(modified, current interface{}, options ...Option) (JSONPatchList, error) {
-	// create a new walker
-	w := &walker{
-		handler:   &DefaultHandler{},
-		predicate: Funcs{},
-		prefix:    []string{""},
-	}
+	return CreateJSONPatchContext(context.Background(), modified, current, options...)
+}
 
-	// apply options to the walker
-	for _, apply := range options {
-		apply(w)
-	}
+// CreateJSONPatchContext creates a JSONPatch like CreateJSONPatch, but aborts the patch creation with the error of the
+// context as soon as it is canceled or its deadline is exceeded. The context is passed to a ContextPredicate or
+// ContextHandler and to the Decider as part of the DecisionContext.
+func CreateJSONPatchContext(ctx context.Context, modified, current interface{}, options ...Option) (JSONPatchList, error) {
+	w := newWalkerContext(ctx, options...)
+	w.modifiedRoot, w.currentRoot = modified, current
 
-	if err := w.walk(reflect.ValueOf(modified), reflect.ValueOf(current), w.prefix); err != nil {
+	if err := w.walk(reflect.ValueOf(modified), reflect.ValueOf(current), reflect.Value{}, w.prefix); err != nil {
 		return JSONPatchList{}, err
 	}
-
-	list := w.patchList
-	if len(list) == 0 {
-		return JSONPatchList{}, nil
+	if err := w.err(); err != nil {
+		return JSONPatchList{}, err
+	}
+	if err := w.redact(w.patchList); err != nil {
+		return JSONPatchList{}, err
 	}
-	raw, err := json.Marshal(list)
 
-	return JSONPatchList{list: list, raw: raw}, err
+	return NewJSONPatchList(w.patchList)
 }
 
-// CreateThreeWayJSONPatch compares three JSON data structures and creates a three-way JSONPatch according to RFC 6902
+// CreateThreeWayJSONPatch compares three JSON data structures and creates a three-way JSONPatch according to RFC 6902.
+// The patch changes the current JSON to match the modified JSON, but only removes values (e.g. struct fields, map
+// entries or slice elements) which are part of the original JSON. Values which were added to the current JSON by someone
+// else are kept. For slices which order is ignored the elements are matched individually by their value (or JSON
+// field), the elements of all other slices are aligned with the elements of the original slice. All indices of the patch
+// refer to the current JSON.
 func CreateThreeWayJSONPatch(modified, current, original interface{}, options ...Option) (JSONPatchList, error) {
-	var list []JSONPatch
+	return CreateThreeWayJSONPatchContext(context.Background(), modified, current, original, options...)
+}
 
-	// create a new walker
-	w := &walker{
-		handler:   &DefaultHandler{},
-		predicate: Funcs{},
-		prefix:    []string{""},
-	}
+// CreateThreeWayJSONPatchContext creates a three-way JSONPatch like CreateThreeWayJSONPatch, but aborts the patch
+// creation with the error of the context as soon as it is canceled or its deadline is exceeded (see
+// CreateJSONPatchContext)
+func CreateThreeWayJSONPatchContext(ctx context.Context, modified, current, original interface{}, options ...Option) (JSONPatchList, error) {
+	result, err := CreateThreeWayJSONPatchResultContext(ctx, modified, current, original, options...)
 
-	// apply options to the walker
-	for _, apply := range options {
-		apply(w)
-	}
+	return result.Patch, err
+}
 
-	// compare modified with current and only keep addition and changes
-	if err := w.walk(reflect.ValueOf(modified), reflect.ValueOf(current), w.prefix); err != nil {
-		return JSONPatchList{}, err
-	}
-	for _, patch := range w.patchList {
-		if patch.Operation != "remove" {
-			list = append(list, patch)
-		}
-	}
+// CreateThreeWayJSONPatchResult creates a three-way JSONPatch like CreateThreeWayJSONPatch and additionally reports all
+// values which were changed in the current JSON as well as in the modified JSON. By default, those conflicts are
+// resolved in favor of the modified JSON, use WithConflictResolver to change the resolution.
+func CreateThreeWayJSONPatchResult(modified, current, original interface{}, options ...Option) (ThreeWayResult, error) {
+	return CreateThreeWayJSONPatchResultContext(context.Background(), modified, current, original, options...)
+}
 
-	// reset walker
-	w.patchList = []JSONPatch{}
+// CreateThreeWayJSONPatchResultContext creates a ThreeWayResult like CreateThreeWayJSONPatchResult, but aborts the patch
+// creation with the error of the context as soon as it is canceled or its deadline is exceeded (see
+// CreateJSONPatchContext)
+func CreateThreeWayJSONPatchResultContext(ctx context.Context, modified, current, original interface{}, options ...Option) (ThreeWayResult, error) {
+	w := newWalkerContext(ctx, options...)
+	w.threeWay = true
+	w.modifiedRoot, w.currentRoot = modified, current
 
-	// compare modified with original and only keep deletions
-	if err := w.walk(reflect.ValueOf(modified), reflect.ValueOf(original), w.prefix); err != nil {
-		return JSONPatchList{}, err
+	if err := w.walk(reflect.ValueOf(modified), reflect.ValueOf(current), reflect.ValueOf(original), w.prefix); err != nil {
+		return ThreeWayResult{Conflicts: w.conflicts}, err
+	}
+	if err := w.err(); err != nil {
+		return ThreeWayResult{Conflicts: w.conflicts}, err
 	}
-	for _, patch := range w.patchList {
-		if patch.Operation == "remove" {
-			list = append(list, patch)
-		}
+	if err := w.redact(w.patchList); err != nil {
+		return ThreeWayResult{Conflicts: w.conflicts}, err
 	}
 
+	list, err := NewJSONPatchList(w.patchList)
+
+	return ThreeWayResult{Patch: list, Conflicts: w.conflicts}, err
+}
+
+// NewJSONPatchList encodes a list of JSONPatch and creates a JSONPatchList
+func NewJSONPatchList(list []JSONPatch) (JSONPatchList, error) {
 	if len(list) == 0 {
 		return JSONPatchList{}, nil
 	}
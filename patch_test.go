@@ -2,6 +2,10 @@ package jsonpatch_test
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -11,6 +15,7 @@ import (
 
 	jsonpatch2 "github.com/evanphx/json-patch/v5"
 	"github.com/go-faker/faker/v4"
+	"github.com/go-faker/faker/v4/pkg/options"
 
 	"github.com/snorwin/jsonpatch"
 )
@@ -83,10 +88,55 @@ type H struct {
 	NotIgnored string `json:"notIgnored"`
 }
 
+type J struct {
+	Name     string   `json:"name"`
+	Status   string   `json:"status,omitempty" jsonpatch:"readonly"`
+	Ignored  int      `json:"ignored" jsonpatch:"ignore"`
+	Atomic   *C       `json:"atomic,omitempty" jsonpatch:"atomic"`
+	Items    []K      `json:"items" jsonpatch:"unordered,key=name"`
+	Strs     []string `json:"strs" jsonpatch:"unordered"`
+	Children []J      `json:"children"`
+}
+
+type K struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+type L struct {
+	Containers []M               `json:"containers" patchStrategy:"merge" patchMergeKey:"name"`
+	Finalizers []string          `json:"finalizers" patchStrategy:"merge"`
+	Selector   map[string]string `json:"selector" patchStrategy:"replace"`
+	Ordered    []M               `json:"ordered" patchStrategy:"merge" patchMergeKey:"name" jsonpatch:""`
+}
+
+type M struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
 type I struct {
 	I interface{} `json:"i"`
 }
 
+// failingHandler is an ErrorHandler which fails to replace values
+type failingHandler struct {
+	jsonpatch.DefaultHandler
+	err error
+}
+
+func (h *failingHandler) Add(pointer jsonpatch.JSONPointer, modified interface{}) ([]jsonpatch.JSONPatch, error) {
+	return h.DefaultHandler.Add(pointer, modified), nil
+}
+
+func (h *failingHandler) Remove(pointer jsonpatch.JSONPointer, current interface{}) ([]jsonpatch.JSONPatch, error) {
+	return h.DefaultHandler.Remove(pointer, current), nil
+}
+
+func (h *failingHandler) Replace(jsonpatch.JSONPointer, interface{}, interface{}) ([]jsonpatch.JSONPatch, error) {
+	return nil, h.err
+}
+
 var _ = Describe("JSONPatch", func() {
 	Context("CreateJsonPatch_pointer_values", func() {
 		It("pointer", func() {
@@ -391,6 +441,213 @@ var _ = Describe("JSONPatch", func() {
 			testPatchWithExpected(G{}, G{B: &B{Str: "don't remove me"}}, G{B: &B{Str: "don't remove me"}}, jsonpatch.WithPredicate(predicate))
 		})
 	})
+	Context("CreateJsonPatch_with_predicate_combinators", func() {
+		var (
+			modified G
+			current  G
+		)
+		BeforeEach(func() {
+			modified = G{B: &B{Str: "new"}, C: C{Str: "new", StrMap: map[string]string{"a": "1", "b": "2"}}}
+			current = G{C: C{Str: "old", StrMap: map[string]string{"a": "2", "c": "3"}}}
+		})
+		It("OnlyPaths", func() {
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithPredicate(jsonpatch.OnlyPaths("/c/strmap/*")))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(ConsistOf(
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/c/strmap/a", Value: "1"},
+				jsonpatch.JSONPatch{Operation: "add", Path: "/c/strmap/b", Value: "2"},
+				jsonpatch.JSONPatch{Operation: "remove", Path: "/c/strmap/c"},
+			))
+
+			list, err = jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithPredicate(jsonpatch.OnlyPaths("/b", "/c/str")))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(ConsistOf(
+				jsonpatch.JSONPatch{Operation: "add", Path: "/b", Value: *modified.B},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/c/str", Value: "new"},
+			))
+		})
+		It("ExcludePaths", func() {
+			testPatchWithExpected(modified, current, G{C: C{Str: "new", StrMap: current.C.StrMap}}, jsonpatch.WithPredicate(jsonpatch.ExcludePaths("/b", "/c/strmap/*")))
+			testPatchWithExpected(modified, current, G{B: modified.B, C: C{Str: "old", StrMap: modified.C.StrMap}}, jsonpatch.WithPredicate(jsonpatch.ExcludePaths("/c/str")))
+		})
+		It("OnlyOps", func() {
+			testPatchWithExpected(modified, current, G{C: C{Str: "new", StrMap: map[string]string{"a": "1", "c": "3"}}}, jsonpatch.WithPredicate(jsonpatch.OnlyOps("replace")))
+			testPatchWithExpected(modified, current, G{B: modified.B, C: C{Str: "old", StrMap: map[string]string{"a": "2", "b": "2"}}}, jsonpatch.WithPredicate(jsonpatch.OnlyOps("add", "remove")))
+		})
+		It("should filter the replaces of whole values", func() {
+			testPatchWithExpected(modified, current, G{B: modified.B, C: current.C}, jsonpatch.WithPredicate(jsonpatch.OnlyOps("add", "remove")), jsonpatch.WithAtomic("/c"))
+			testPatchWithExpected(modified, current, G{B: modified.B, C: current.C}, jsonpatch.WithPredicate(jsonpatch.Not(jsonpatch.OnlyOps("replace"))), jsonpatch.WithAtomic("/c"))
+			testPatchWithExpected(modified, current, G{B: modified.B, C: current.C}, jsonpatch.WithPredicate(jsonpatch.And(jsonpatch.ExcludePaths("/a"), jsonpatch.OnlyOps("add", "remove"))), jsonpatch.WithMaxDepth(1))
+			testPatchWithExpected(modified, current, modified, jsonpatch.WithPredicate(jsonpatch.Or(jsonpatch.OnlyOps("add"), jsonpatch.OnlyOps("replace"), jsonpatch.OnlyOps("remove"))), jsonpatch.WithAtomic("/c"))
+		})
+		It("And, Or and Not", func() {
+			testPatchWithExpected(modified, current, G{C: C{Str: "old", StrMap: map[string]string{"a": "1", "c": "3"}}}, jsonpatch.WithPredicate(jsonpatch.And(jsonpatch.OnlyPaths("/c/strmap/*"), jsonpatch.OnlyOps("replace"))))
+			testPatchWithExpected(modified, current, G{B: modified.B, C: C{Str: "new", StrMap: map[string]string{"a": "1", "c": "3"}}}, jsonpatch.WithPredicate(jsonpatch.Or(jsonpatch.OnlyPaths("/b"), jsonpatch.Not(jsonpatch.OnlyOps("add", "remove")))))
+			testPatchWithExpected(modified, current, G{B: modified.B, C: C{Str: "new", StrMap: current.C.StrMap}}, jsonpatch.WithPredicate(jsonpatch.Not(jsonpatch.OnlyPaths("/c/strmap/*"))))
+		})
+		It("multiple predicates", func() {
+			testPatchWithExpected(modified, current, G{C: C{Str: "old", StrMap: map[string]string{"a": "1", "c": "3"}}}, jsonpatch.WithPredicate(jsonpatch.OnlyPaths("/c/strmap/*")), jsonpatch.WithPredicate(jsonpatch.OnlyOps("replace")))
+		})
+	})
+	Context("CreateJsonPatch_with_immutable_and_read_only", func() {
+		var (
+			modified G
+			current  G
+		)
+		BeforeEach(func() {
+			modified = G{B: &B{Str: "new"}, C: C{Str: "new", StrMap: map[string]string{"a": "1", "b": "2"}}}
+			current = G{C: C{Str: "old", StrMap: map[string]string{"a": "2", "c": "3"}}}
+		})
+		It("should fail with every immutable value which would change", func() {
+			_, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.Immutable("/b", "/c/strmap/*"))
+			var immutableErr *jsonpatch.ImmutableError
+			Ω(errors.As(err, &immutableErr)).Should(BeTrue())
+			Ω(immutableErr.Pointers).Should(ConsistOf(
+				jsonpatch.ParseJSONPointer("/b"),
+				jsonpatch.ParseJSONPointer("/c/strmap/a"),
+				jsonpatch.ParseJSONPointer("/c/strmap/b"),
+				jsonpatch.ParseJSONPointer("/c/strmap/c"),
+			))
+			Ω(err).Should(MatchError(ContainSubstring("/c/strmap/b")))
+
+			_, err = jsonpatch.CreateThreeWayJSONPatch(modified, current, current, jsonpatch.Immutable("/c/str"))
+			Ω(err).Should(MatchError("immutable values would change at: /c/str"))
+		})
+		It("should not fail if immutable values don't change", func() {
+			testPatchWithExpected(modified, current, modified, jsonpatch.Immutable("/c/intmap", "/d"))
+			testPatchWithExpected(G{B: modified.B, C: C{Str: "new", StrMap: current.C.StrMap}}, current, G{B: modified.B, C: C{Str: "new", StrMap: current.C.StrMap}}, jsonpatch.Immutable("/c/strmap"))
+		})
+		It("should drop the patches of read-only values", func() {
+			testPatchWithExpected(modified, current, G{C: C{Str: "new", StrMap: current.C.StrMap}}, jsonpatch.ReadOnly("/b", "/c/strmap"))
+		})
+		It("should fail if immutable values would change with values which are patched as a whole", func() {
+			expectImmutable := func(modified, current interface{}, pointers []string, options ...jsonpatch.Option) {
+				_, err := jsonpatch.CreateJSONPatch(modified, current, options...)
+				var immutableErr *jsonpatch.ImmutableError
+				Ω(errors.As(err, &immutableErr)).Should(BeTrue())
+				Ω(immutableErr.Pointers).Should(HaveLen(len(pointers)))
+				for j, pointer := range pointers {
+					Ω(immutableErr.Pointers[j].String()).Should(Equal(pointer))
+				}
+			}
+
+			// atomic
+			expectImmutable(modified, current, []string{"/c/str"}, jsonpatch.Immutable("/c/str"), jsonpatch.WithAtomic("/c"))
+			expectImmutable(modified, current, []string{"/c/strmap/b", "/c/strmap/c"}, jsonpatch.Immutable("/c/strmap/b", "/c/strmap/c"), jsonpatch.WithAtomic("/c/strmap"))
+			// max depth
+			expectImmutable(modified, current, []string{"/c/strmap/a", "/c/strmap/b", "/c/strmap/c"}, jsonpatch.Immutable("/c/strmap/*"), jsonpatch.WithMaxDepth(1))
+			// jsonpatch:"atomic" tag
+			expectImmutable(J{Atomic: &C{Str: "new"}}, J{Atomic: &C{Str: "old"}}, []string{"/atomic/str"}, jsonpatch.Immutable("/atomic/str"))
+			// patchStrategy:"replace" tag
+			expectImmutable(N{Spec: L{Selector: map[string]string{"app": "a"}}}, N{Spec: L{Selector: map[string]string{"app": "b"}}}, []string{"/spec/selector/app"}, jsonpatch.Immutable("/spec/selector/app"))
+			// ReplaceWhole decision
+			expectImmutable(modified, current, []string{"/c/str"}, jsonpatch.Immutable("/c/str"), jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(context jsonpatch.DecisionContext) jsonpatch.Decision {
+				if context.Pointer.String() == "/c" {
+					return jsonpatch.ReplaceWhole
+				}
+				return jsonpatch.Include
+			})))
+			// collapse
+			expectImmutable(modified, current, []string{"/c/str"}, jsonpatch.Immutable("/c/str"), jsonpatch.WithCollapseThreshold(0.01))
+			// add and remove
+			expectImmutable(G{C: C{Str: "new"}}, G{}, []string{"/c/str"}, jsonpatch.Immutable("/c/str"), jsonpatch.WithAtomic("/c"))
+			expectImmutable(J{}, J{Atomic: &C{Str: "old"}}, []string{"/atomic/str"}, jsonpatch.Immutable("/atomic/str"))
+
+			// unchanged immutable values don't fail
+			testPatchWithExpected(G{C: C{Str: "old", StrMap: modified.C.StrMap}}, current, G{C: C{Str: "old", StrMap: modified.C.StrMap}}, jsonpatch.Immutable("/c/str"), jsonpatch.WithAtomic("/c"))
+		})
+		It("should drop the patches of values which are patched as a whole if read-only values would change", func() {
+			testPatchWithExpected(modified, current, G{B: modified.B, C: current.C}, jsonpatch.ReadOnly("/c/str"), jsonpatch.WithAtomic("/c"))
+			testPatchWithExpected(modified, current, G{B: modified.B, C: current.C}, jsonpatch.ReadOnly("/c/strmap/c"), jsonpatch.WithMaxDepth(1))
+			testPatchWithExpected(J{Atomic: &C{Str: "new"}}, J{Atomic: &C{Str: "old"}}, J{Atomic: &C{Str: "old"}}, jsonpatch.ReadOnly("/atomic/str"))
+			testPatchWithExpected(G{C: C{Str: "old", StrMap: modified.C.StrMap}}, current, G{C: C{Str: "old", StrMap: modified.C.StrMap}}, jsonpatch.ReadOnly("/c/str"), jsonpatch.WithAtomic("/c"))
+		})
+	})
+	Context("CreateJsonPatch_with_error_predicates", func() {
+		var (
+			errInvalid = errors.New("invalid")
+			predicate  jsonpatch.ErrorPredicate
+		)
+		BeforeEach(func() {
+			predicate = jsonpatch.ErrorFuncs{
+				ReplaceFunc: func(_ jsonpatch.JSONPointer, modified, _ interface{}) (bool, error) {
+					if str, ok := modified.(string); ok && str == "invalid" {
+						return false, errInvalid
+					}
+
+					return true, nil
+				},
+			}
+		})
+		It("should create the patch without errors", func() {
+			testPatchWithExpected(G{C: C{Str: "new"}}, G{C: C{Str: "old"}}, G{C: C{Str: "new"}}, jsonpatch.WithErrorPredicate(predicate))
+		})
+		It("should abort with the first error", func() {
+			_, err := jsonpatch.CreateJSONPatch([]string{"invalid", "invalid"}, []string{"a", "b"}, jsonpatch.WithErrorPredicate(predicate))
+			Ω(errors.Is(err, errInvalid)).Should(BeTrue())
+			Ω(err.Error()).Should(Equal("invalid at: /0"))
+
+			_, err = jsonpatch.CreateThreeWayJSONPatch(G{C: C{Str: "invalid"}}, G{C: C{Str: "a"}}, G{C: C{Str: "a"}}, jsonpatch.WithErrorPredicate(predicate))
+			Ω(errors.Is(err, errInvalid)).Should(BeTrue())
+			Ω(err.Error()).Should(Equal("invalid at: /c/str"))
+		})
+		It("should not filter or patch the siblings after the first error", func() {
+			calls := 0
+			_, err := jsonpatch.CreateJSONPatch([]string{"invalid", "x", "y"}, []string{"a"}, jsonpatch.WithErrorPredicate(predicate), jsonpatch.WithPredicate(jsonpatch.Funcs{
+				AddFunc: func(_ jsonpatch.JSONPointer, _ interface{}) bool {
+					calls++
+					return true
+				},
+			}))
+			Ω(err).Should(MatchError(errInvalid))
+			Ω(calls).Should(BeZero())
+		})
+		It("should collect all errors", func() {
+			_, err := jsonpatch.CreateJSONPatch([]string{"invalid", "c", "invalid"}, []string{"a", "b", "c"}, jsonpatch.WithErrorPredicate(predicate), jsonpatch.WithCollectErrors())
+			Ω(errors.Is(err, errInvalid)).Should(BeTrue())
+			Ω(err.Error()).Should(Equal("invalid at: /0\ninvalid at: /2"))
+		})
+		It("should collect the errors of deciders", func() {
+			_, err := jsonpatch.CreateJSONPatch([]string{"invalid", "c", "invalid"}, []string{"a", "b", "c"}, jsonpatch.WithCollectErrors(), jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(context jsonpatch.DecisionContext) jsonpatch.Decision {
+				if context.Modified == "invalid" {
+					return jsonpatch.Fail
+				}
+				return jsonpatch.Include
+			})))
+			Ω(errors.Is(err, jsonpatch.ErrDecisionFailed)).Should(BeTrue())
+			Ω(err).Should(MatchError(ContainSubstring("/0")))
+			Ω(err).Should(MatchError(ContainSubstring("/2")))
+		})
+		It("should abort with the error of the decider", func() {
+			_, err := jsonpatch.CreateJSONPatch([]string{"invalid", "c", "invalid"}, []string{"a", "b", "c"}, jsonpatch.WithCollectErrors(), jsonpatch.WithErrorDecider(jsonpatch.ErrorDeciderFunc(func(context jsonpatch.DecisionContext) (jsonpatch.Decision, error) {
+				if context.Modified == "invalid" {
+					return jsonpatch.Fail, errInvalid
+				}
+				return jsonpatch.Include, nil
+			})))
+			Ω(errors.Is(err, errInvalid)).Should(BeTrue())
+			Ω(errors.Is(err, jsonpatch.ErrDecisionFailed)).Should(BeFalse())
+			Ω(err.Error()).Should(Equal("invalid at: /0\ninvalid at: /2"))
+
+			_, err = jsonpatch.CreateJSONPatch(G{C: C{Str: "new"}}, G{C: C{Str: "old"}}, jsonpatch.WithErrorDecider(jsonpatch.ErrorDeciderFunc(func(context jsonpatch.DecisionContext) (jsonpatch.Decision, error) {
+				if context.Pointer.String() == "/c" {
+					return jsonpatch.Include, errInvalid
+				}
+				return jsonpatch.Include, nil
+			})))
+			Ω(err).Should(MatchError(errInvalid))
+			Ω(err.Error()).Should(Equal("invalid at: /c"))
+		})
+		It("should abort with the error of the handler", func() {
+			_, err := jsonpatch.CreateJSONPatch(G{C: C{Str: "new"}}, G{C: C{Str: "old"}}, jsonpatch.WithErrorHandler(&failingHandler{err: errInvalid}))
+			Ω(errors.Is(err, errInvalid)).Should(BeTrue())
+			Ω(err.Error()).Should(Equal("invalid at: /c/str"))
+
+			list, err := jsonpatch.CreateJSONPatch(G{C: C{Str: "new"}}, G{}, jsonpatch.WithErrorHandler(&failingHandler{err: errInvalid}))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "add", Path: "/c/str", Value: "new"}}))
+		})
+	})
 	Context("CreateJsonPatch_with_prefix", func() {
 		It("empty prefix", func() {
 			testPatchWithExpected(G{B: &B{Bool: true, Str: "str"}}, G{}, G{B: &B{Bool: true, Str: "str"}}, jsonpatch.WithPrefix([]string{""}))
@@ -442,6 +699,214 @@ var _ = Describe("JSONPatch", func() {
 			Ω(patchedJSON).Should(MatchJSON(expectedJSON))
 		})
 	})
+	Context("CreateJsonPatch_with_collapse_threshold", func() {
+		It("should replace a value if its patches are larger", func() {
+			modified := C{StrMap: map[string]string{"a": "4", "b": "5", "c": "6"}}
+			current := C{StrMap: map[string]string{"a": "1", "b": "2", "c": "3"}}
+			testPatchWithExpected(modified, current, modified, jsonpatch.WithCollapseThreshold(1))
+
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithCollapseThreshold(1))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "replace", Path: "/strmap", Value: modified.StrMap}}))
+		})
+		It("should keep the patches if they are smaller", func() {
+			modified := D{StringSlice: []string{"element a", "element b", "element c", "element d", "element e", "F", "G"}}
+			current := D{StringSlice: []string{"element a", "element b", "element c", "element d", "element e", "f", "g"}}
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithCollapseThreshold(1))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.Len()).Should(Equal(2))
+
+			modified.StringSlice = []string{"A", "B", "C", "D", "E", "F", "G"}
+			list, err = jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithCollapseThreshold(100))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.Len()).Should(Equal(7))
+		})
+		It("should not replace the root value", func() {
+			list, err := jsonpatch.CreateJSONPatch(B{Str: "a", Int: 1}, B{Str: "b", Int: 2}, jsonpatch.WithCollapseThreshold(1))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.Len()).Should(Equal(2))
+		})
+		It("should not replace values rejected by the predicate", func() {
+			modified := C{StrMap: map[string]string{"a": "4", "b": "5", "c": "6"}}
+			current := C{StrMap: map[string]string{"a": "1", "b": "2", "c": "3"}}
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithCollapseThreshold(1), jsonpatch.WithPredicate(jsonpatch.Funcs{
+				ReplaceFunc: func(pointer jsonpatch.JSONPointer, _, _ interface{}) bool {
+					return pointer.String() != "/strmap"
+				},
+			}))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.Len()).Should(Equal(3))
+		})
+		It("should only check the replace if it is taken", func() {
+			modified := D{StringSlice: []string{"element a", "element b", "element c", "element d", "element e", "F", "G"}}
+			current := D{StringSlice: []string{"element a", "element b", "element c", "element d", "element e", "f", "g"}}
+			replaces := 0
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithCollapseThreshold(1), jsonpatch.WithPredicate(jsonpatch.Funcs{
+				ReplaceFunc: func(pointer jsonpatch.JSONPointer, _, _ interface{}) bool {
+					if pointer.String() == "/strs" {
+						replaces++
+					}
+					return true
+				},
+			}), jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(context jsonpatch.DecisionContext) jsonpatch.Decision {
+				if context.Pointer.String() == "/strs" && context.Operation == "replace" {
+					return jsonpatch.Fail
+				}
+				return jsonpatch.Include
+			})))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.Len()).Should(Equal(2))
+			// the predicate is only checked before walking into the slice
+			Ω(replaces).Should(Equal(1))
+		})
+		It("should not replace values which contain immutable values", func() {
+			modified := C{StrMap: map[string]string{"a": "1", "b": "5", "c": "6"}}
+			current := C{StrMap: map[string]string{"a": "1", "b": "2", "c": "3"}}
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithCollapseThreshold(1), jsonpatch.Immutable("/strmap/a"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(ConsistOf(
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/strmap/b", Value: "5"},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/strmap/c", Value: "6"},
+			))
+		})
+		for i := 0; i < 20; i++ {
+			It("fuzzy "+strconv.Itoa(i), func() {
+				var current, modified G
+				Ω(faker.FakeData(&current, options.WithRandomMapAndSliceMaxSize(5))).Should(Succeed())
+				Ω(faker.FakeData(&modified, options.WithRandomMapAndSliceMaxSize(5))).Should(Succeed())
+				testPatchWithExpected(modified, current, modified, jsonpatch.WithCollapseThreshold(1))
+
+				list, err := jsonpatch.CreateJSONPatch(modified, current)
+				Ω(err).ShouldNot(HaveOccurred())
+				collapsed, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithCollapseThreshold(1))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(len(collapsed.Raw())).Should(BeNumerically("<=", len(list.Raw())))
+			})
+		}
+	})
+	Context("CreateJsonPatch_with_max_depth_and_atomic", func() {
+		It("should replace values beyond the max depth", func() {
+			modified := G{C: C{Str: "a", StrMap: map[string]string{"a": "1"}}}
+			testPatchWithExpected(modified, G{}, modified, jsonpatch.WithMaxDepth(1))
+
+			list, err := jsonpatch.CreateJSONPatch(modified, G{}, jsonpatch.WithMaxDepth(1))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "replace", Path: "/c", Value: modified.C}}))
+		})
+		It("should add and remove pointers beyond the max depth", func() {
+			testPatchWithExpected(G{B: &B{Str: "a"}}, G{}, G{B: &B{Str: "a"}}, jsonpatch.WithMaxDepth(1))
+			testPatchWithExpected(G{}, G{B: &B{Str: "a"}}, G{}, jsonpatch.WithMaxDepth(1))
+			testPatchWithExpected(G{B: &B{Str: "a"}}, G{B: &B{Str: "b"}}, G{B: &B{Str: "a"}}, jsonpatch.WithMaxDepth(1))
+		})
+		It("should replace values at atomic pointers", func() {
+			modified := C{StrMap: map[string]string{"a": "1", "b": "2"}, StructMap: map[string]B{"a": {Str: "a", Int: 1}}}
+			current := C{StrMap: map[string]string{"a": "2"}, StructMap: map[string]B{"a": {Str: "b", Int: 2}}}
+			testPatchWithExpected(modified, current, modified, jsonpatch.WithAtomic("/strmap", "/structmap/*"))
+
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithAtomic("/strmap", "/structmap/*"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(ConsistOf(
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/strmap", Value: modified.StrMap},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/structmap/a", Value: modified.StructMap["a"]},
+			))
+		})
+		It("should not patch equal atomic values", func() {
+			list, err := jsonpatch.CreateJSONPatch(D{StringSlice: []string{"a", "b"}}, D{StringSlice: []string{"b", "a"}}, jsonpatch.WithAtomic("/strs"), jsonpatch.IgnoreSliceOrder())
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.Empty()).Should(BeTrue())
+		})
+		It("should replace atomic values in three-way patches", func() {
+			testThreeWayPatchWithExpected(
+				C{StrMap: map[string]string{"a": "1"}},
+				C{StrMap: map[string]string{"a": "2", "b": "3"}},
+				C{StrMap: map[string]string{"a": "2"}},
+				C{StrMap: map[string]string{"a": "1"}},
+				jsonpatch.WithAtomic("/strmap"),
+			)
+		})
+		for i := 0; i < 20; i++ {
+			It("fuzzy "+strconv.Itoa(i), func() {
+				var current, modified G
+				Ω(faker.FakeData(&current, options.WithRandomMapAndSliceMaxSize(5))).Should(Succeed())
+				Ω(faker.FakeData(&modified, options.WithRandomMapAndSliceMaxSize(5))).Should(Succeed())
+				testPatchWithExpected(modified, current, modified, jsonpatch.WithMaxDepth(3), jsonpatch.WithAtomic("/d/*"))
+			})
+		}
+	})
+	Context("CreateJsonPatch_with_tags", func() {
+		It("should not patch ignored and readonly fields", func() {
+			list, err := jsonpatch.CreateJSONPatch(J{Name: "a", Status: "ready", Ignored: 1}, J{Name: "b", Status: "pending"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "replace", Path: "/name", Value: "a"}}))
+		})
+		It("should patch atomic fields as a whole", func() {
+			modified := J{Atomic: &C{Str: "a", StrMap: map[string]string{"a": "1"}}}
+			current := J{Atomic: &C{Str: "b"}}
+			testPatchWithExpected(modified, current, modified)
+
+			list, err := jsonpatch.CreateJSONPatch(modified, current)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "replace", Path: "/atomic", Value: *modified.Atomic}}))
+		})
+		It("should ignore the order of unordered fields", func() {
+			modified := J{Items: []K{{Name: "b", Value: 2}, {Name: "a", Value: 3}}, Strs: []string{"y", "x"}}
+			current := J{Items: []K{{Name: "a", Value: 1}, {Name: "b", Value: 2}}, Strs: []string{"x", "y"}}
+			list, err := jsonpatch.CreateJSONPatch(modified, current)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "replace", Path: "/items/0/value", Value: int64(3)}}))
+		})
+		It("should apply the tags of nested values", func() {
+			modified := J{Children: []J{{Name: "a", Strs: []string{"y", "x", "z"}, Status: "ready"}}}
+			current := J{Children: []J{{Name: "a", Strs: []string{"x", "y"}}}}
+			list, err := jsonpatch.CreateJSONPatch(modified, current)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "add", Path: "/children/0/strs/2", Value: "z"}}))
+		})
+		It("should fail for unsupported tag options", func() {
+			_, err := jsonpatch.CreateJSONPatch(struct {
+				Str string `json:"str" jsonpatch:"unknown"`
+			}{}, struct {
+				Str string `json:"str" jsonpatch:"unknown"`
+			}{})
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+	Context("CreateJsonPatch_with_kubernetes_tags", func() {
+		It("should match the elements of merge slices by their merge key", func() {
+			modified := L{Containers: []M{{Name: "sidecar", Image: "b"}, {Name: "main", Image: "c"}}, Finalizers: []string{"y", "x"}}
+			current := L{Containers: []M{{Name: "main", Image: "a"}, {Name: "sidecar", Image: "b"}}, Finalizers: []string{"x", "y"}}
+			list, err := jsonpatch.CreateJSONPatch(modified, current)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "replace", Path: "/containers/0/image", Value: "c"}}))
+		})
+		It("should diff merge slices with duplicated merge keys in order", func() {
+			// e.g. ports with the same container port but different protocols
+			modified := L{Containers: []M{{Name: "main", Image: "a"}, {Name: "main", Image: "c"}}, Finalizers: []string{"x", "x", "z"}}
+			current := L{Containers: []M{{Name: "main", Image: "a"}, {Name: "main", Image: "b"}}, Finalizers: []string{"x", "x"}}
+			testPatchWithExpected(modified, current, modified)
+
+			list, err := jsonpatch.CreateJSONPatch(modified, current)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{
+				{Operation: "replace", Path: "/containers/1/image", Value: "c"},
+				{Operation: "add", Path: "/finalizers/2", Value: "z"},
+			}))
+		})
+		It("should replace values with the replace strategy as a whole", func() {
+			modified := L{Selector: map[string]string{"app": "a", "tier": "b"}}
+			current := L{Selector: map[string]string{"app": "b"}}
+			list, err := jsonpatch.CreateJSONPatch(modified, current)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(Equal([]jsonpatch.JSONPatch{{Operation: "replace", Path: "/selector", Value: modified.Selector}}))
+		})
+		It("should prefer the jsonpatch tag", func() {
+			modified := L{Ordered: []M{{Name: "b"}, {Name: "a"}}}
+			current := L{Ordered: []M{{Name: "a"}, {Name: "b"}}}
+			list, err := jsonpatch.CreateJSONPatch(modified, current)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.Len()).Should(Equal(2))
+		})
+	})
 	Context("CreateJsonPatch_errors", func() {
 		It("not matching types", func() {
 			_, err := jsonpatch.CreateJSONPatch(A{}, B{})
@@ -522,6 +987,101 @@ var _ = Describe("JSONPatch", func() {
 			testThreeWayPatchWithExpected(D{IntSlice: []int{1, 2, 3}, StringSlice: []string{}}, D{IntSlice: []int{1, 2, 3}, StringSlice: []string{"str1"}}, D{IntSlice: []int{1, 2, 3}, StringSlice: []string{"str1"}}, D{IntSlice: []int{1, 2, 3}, StringSlice: []string{}})
 		})
 	})
+	Context("CreateThreeWayJSONPatch_ignore_slice_order", func() {
+		It("should keep elements added to current", func() {
+			testThreeWayPatchWithExpected([]int{1, 2, 3, 5}, []int{4, 3, 2, 1}, []int{1, 2, 3}, []int{4, 3, 2, 1, 5}, jsonpatch.IgnoreSliceOrder())
+			testThreeWayPatchWithExpected(D{StructSliceWithKey: []C{{Str: "key1"}, {Str: "key2", StrMap: map[string]string{"key": "value"}}}}, D{StructSliceWithKey: []C{{Str: "key3"}, {Str: "key2"}, {Str: "key1"}}}, D{StructSliceWithKey: []C{{Str: "key1"}, {Str: "key2"}}}, D{StructSliceWithKey: []C{{Str: "key3"}, {Str: "key2", StrMap: map[string]string{"key": "value"}}, {Str: "key1"}}}, jsonpatch.IgnoreSliceOrderWithPattern([]jsonpatch.IgnorePattern{{"/structsWithKey", "str"}}))
+		})
+		It("should only remove elements removed from original", func() {
+			testThreeWayPatchWithExpected([]int{1, 3}, []int{4, 3, 2, 1}, []int{1, 2, 3}, []int{4, 3, 1}, jsonpatch.IgnoreSliceOrder())
+			testThreeWayPatchWithExpected([]int{3}, []int{3, 4}, []int{1, 2, 3}, []int{3, 4}, jsonpatch.IgnoreSliceOrder())
+			testThreeWayPatchWithExpected(D{StructSliceWithKey: []C{{Str: "key1"}}}, D{StructSliceWithKey: []C{{Str: "key3"}, {Str: "key2"}, {Str: "key1"}, {Str: "key4"}}}, D{StructSliceWithKey: []C{{Str: "key1"}, {Str: "key2"}}}, D{StructSliceWithKey: []C{{Str: "key3"}, {Str: "key1"}, {Str: "key4"}}}, jsonpatch.IgnoreSliceOrderWithPattern([]jsonpatch.IgnorePattern{{"/structsWithKey", "str"}}))
+			testThreeWayPatchWithExpected(D{PtrSliceWithKey: []*B{}}, D{PtrSliceWithKey: []*B{{Str: "key2"}, {Str: "key1"}}}, D{PtrSliceWithKey: []*B{{Str: "key1"}}}, D{PtrSliceWithKey: []*B{{Str: "key2"}}}, jsonpatch.IgnoreSliceOrderWithPattern([]jsonpatch.IgnorePattern{{"/ptrWithKey", "str"}}))
+		})
+		It("should update elements in current", func() {
+			testThreeWayPatchWithExpected(D{StructSliceWithKey: []C{{Str: "key1", StrMap: map[string]string{"key": "new"}}}}, D{StructSliceWithKey: []C{{Str: "key2"}, {Str: "key1", StrMap: map[string]string{"key": "old", "other": "value"}}}}, D{StructSliceWithKey: []C{{Str: "key1", StrMap: map[string]string{"key": "old"}}}}, D{StructSliceWithKey: []C{{Str: "key2"}, {Str: "key1", StrMap: map[string]string{"key": "new", "other": "value"}}}}, jsonpatch.IgnoreSliceOrderWithPattern([]jsonpatch.IgnorePattern{{"/structsWithKey", "str"}}))
+		})
+	})
+	Context("CreateThreeWayJSONPatch_slice_index", func() {
+		It("should compute indices against current", func() {
+			// insert in current
+			testThreeWayPatchWithExpected(D{IntSlice: []int{1, 3}}, D{IntSlice: []int{0, 1, 2, 3}}, D{IntSlice: []int{1, 2, 3}}, D{IntSlice: []int{0, 1, 3}})
+			testThreeWayPatchWithExpected(D{IntSlice: []int{1, 2, 3, 4}}, D{IntSlice: []int{0, 1, 2, 3}}, D{IntSlice: []int{1, 2, 3}}, D{IntSlice: []int{0, 1, 2, 3, 4}})
+			testThreeWayPatchWithExpected(D{IntSlice: []int{1, 4, 3}}, D{IntSlice: []int{0, 1, 2, 3}}, D{IntSlice: []int{1, 2, 3}}, D{IntSlice: []int{0, 1, 4, 3}})
+			// append in current
+			testThreeWayPatchWithExpected(D{IntSlice: []int{1, 3}}, D{IntSlice: []int{1, 2, 3, 4}}, D{IntSlice: []int{1, 2, 3}}, D{IntSlice: []int{1, 3, 4}})
+			testThreeWayPatchWithExpected(D{IntSlice: []int{}}, D{IntSlice: []int{1, 2, 3, 4}}, D{IntSlice: []int{1, 2, 3}}, D{IntSlice: []int{4}})
+			// remove in current
+			testThreeWayPatchWithExpected(D{IntSlice: []int{1, 2, 4}}, D{IntSlice: []int{2, 3}}, D{IntSlice: []int{1, 2, 3}}, D{IntSlice: []int{2, 4}})
+			testThreeWayPatchWithExpected(D{IntSlice: []int{2, 3}}, D{IntSlice: []int{1, 3}}, D{IntSlice: []int{1, 2, 3}}, D{IntSlice: []int{3}})
+			// change in current
+			testThreeWayPatchWithExpected(D{IntSlice: []int{1, 2}}, D{IntSlice: []int{1, 5, 3}}, D{IntSlice: []int{1, 2, 3}}, D{IntSlice: []int{1, 5}})
+			testThreeWayPatchWithExpected(D{StructSlice: []C{{Str: "a"}}}, D{StructSlice: []C{{Str: "x"}, {Str: "a"}, {Str: "b", StrMap: map[string]string{"key": "value"}}}}, D{StructSlice: []C{{Str: "a"}, {Str: "b"}}}, D{StructSlice: []C{{Str: "x"}, {Str: "a"}}})
+			testThreeWayPatchWithExpected(D{StructSlice: []C{{Str: "a", StrMap: map[string]string{"key": "value"}}, {Str: "b"}}}, D{StructSlice: []C{{Str: "x"}, {Str: "a"}, {Str: "b"}}}, D{StructSlice: []C{{Str: "a"}, {Str: "b"}}}, D{StructSlice: []C{{Str: "x"}, {Str: "a", StrMap: map[string]string{"key": "value"}}, {Str: "b"}}})
+		})
+		It("should merge added elements", func() {
+			testThreeWayPatchWithExpected(D{IntSlice: []int{1, 2, 3}}, D{IntSlice: []int{1, 2}}, D{}, D{IntSlice: []int{1, 2, 3}})
+			testThreeWayPatchWithExpected(D{IntSlice: []int{1, 2}}, D{IntSlice: []int{1, 3}}, D{IntSlice: []int{1}}, D{IntSlice: []int{1, 2, 3}})
+			testThreeWayPatchWithExpected(D{IntSlice: []int{0, 1, 2}}, D{IntSlice: []int{1, 3, 2}}, D{IntSlice: []int{1, 2}}, D{IntSlice: []int{0, 1, 3, 2}})
+		})
+		It("should not patch unchanged elements", func() {
+			testThreeWayPatchWithExpected(D{IntSlice: []int{1, 2}}, D{IntSlice: []int{1, 2}}, D{}, D{IntSlice: []int{1, 2}})
+			testThreeWayPatchWithExpected(D{IntSlice: []int{1, 2, 3}}, D{IntSlice: []int{1, 5, 3}}, D{IntSlice: []int{1, 2, 3}}, D{IntSlice: []int{1, 5, 3}})
+			testThreeWayPatchWithExpected(D{IntSlice: []int{1, 2, 3, 4}}, D{IntSlice: []int{1, 2, 3, 4}}, D{IntSlice: []int{1, 2, 3}}, D{IntSlice: []int{1, 2, 3, 4}})
+		})
+		It("should match elements which only differ by nil and empty values", func() {
+			testThreeWayPatchWithExpected(D{StructSlice: []C{{Str: "a"}, {Str: "b"}}}, D{StructSlice: []C{{Str: "x"}, {Str: "a", StrMap: map[string]string{}}}}, D{StructSlice: []C{{Str: "a", StrMap: map[string]string{}}}}, D{StructSlice: []C{{Str: "x"}, {Str: "a", StrMap: map[string]string{}}, {Str: "b"}}})
+			testThreeWayPatchWithExpected(D{FloatSlice: []float64{0, 2}}, D{FloatSlice: []float64{1, math.Copysign(0, -1)}}, D{FloatSlice: []float64{0}}, D{FloatSlice: []float64{1, 0, 2}})
+		})
+	})
+	Context("CreateThreeWayJSONPatchResult_conflicts", func() {
+		It("should report conflicts", func() {
+			result, err := jsonpatch.CreateThreeWayJSONPatchResult(B{Str: "ours", Int: 1, Bool: true}, B{Str: "theirs", Int: 2}, B{Str: "original", Int: 3})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(result.Conflicts).Should(ConsistOf(
+				jsonpatch.Conflict{Pointer: jsonpatch.ParseJSONPointer("/str"), Original: "original", Current: "theirs", Modified: "ours"},
+				jsonpatch.Conflict{Pointer: jsonpatch.ParseJSONPointer("/int"), Original: 3, Current: 2, Modified: 1},
+			))
+			Ω(result.Patch.Len()).Should(Equal(3))
+		})
+		It("should report remove conflicts", func() {
+			result, err := jsonpatch.CreateThreeWayJSONPatchResult(A{}, A{B: &B{Str: "theirs"}}, A{B: &B{Str: "original"}})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(result.Conflicts).Should(ConsistOf(jsonpatch.Conflict{Pointer: jsonpatch.ParseJSONPointer("/ptr"), Original: B{Str: "original"}, Current: B{Str: "theirs"}}))
+			result, err = jsonpatch.CreateThreeWayJSONPatchResult(D{IntSlice: []int{1}}, D{IntSlice: []int{1, 4, 3}}, D{IntSlice: []int{1, 2}})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(result.Conflicts).Should(ConsistOf(jsonpatch.Conflict{Pointer: jsonpatch.ParseJSONPointer("/ints/1"), Original: 2, Current: 4}))
+		})
+		It("should not report changes of either side", func() {
+			result, err := jsonpatch.CreateThreeWayJSONPatchResult(B{Str: "ours", Int: 2}, B{Str: "original", Int: 2}, B{Str: "original", Int: 1})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(result.Conflicts).Should(BeEmpty())
+			result, err = jsonpatch.CreateThreeWayJSONPatchResult(B{Str: "same"}, B{Str: "same"}, B{Str: "original"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(result.Conflicts).Should(BeEmpty())
+			Ω(result.Patch.Empty()).Should(BeTrue())
+		})
+		It("should resolve conflicts", func() {
+			// ours
+			testThreeWayPatchWithExpected(B{Str: "ours", Int: 1}, B{Str: "theirs", Int: 2}, B{Str: "original"}, B{Str: "ours", Int: 1})
+			testThreeWayPatchWithExpected(B{Str: "ours", Int: 1}, B{Str: "theirs", Int: 2}, B{Str: "original"}, B{Str: "ours", Int: 1}, jsonpatch.WithConflictResolver(jsonpatch.ResolveOurs))
+			testThreeWayPatchWithExpected(A{}, A{B: &B{Str: "theirs"}}, A{B: &B{Str: "original"}}, A{}, jsonpatch.WithConflictResolver(jsonpatch.ResolveOurs))
+			// theirs
+			testThreeWayPatchWithExpected(B{Str: "ours", Int: 1, Bool: true}, B{Str: "theirs", Int: 2}, B{Str: "original"}, B{Str: "theirs", Int: 2, Bool: true}, jsonpatch.WithConflictResolver(jsonpatch.ResolveTheirs))
+			testThreeWayPatchWithExpected(A{}, A{B: &B{Str: "theirs"}}, A{B: &B{Str: "original"}}, A{B: &B{Str: "theirs"}}, jsonpatch.WithConflictResolver(jsonpatch.ResolveTheirs))
+			// custom
+			testThreeWayPatchWithExpected(B{Str: "ours", Int: 1}, B{Str: "theirs", Int: 2}, B{Str: "original"}, B{Str: "theirs", Int: 1}, jsonpatch.WithConflictResolver(func(conflict jsonpatch.Conflict) (bool, error) {
+				return conflict.Pointer.String() == "/int", nil
+			}))
+		})
+		It("should fail on conflicts", func() {
+			result, err := jsonpatch.CreateThreeWayJSONPatchResult(B{Str: "ours"}, B{Str: "theirs"}, B{Str: "original"}, jsonpatch.WithConflictResolver(jsonpatch.ResolveFail))
+			Ω(err).Should(MatchError(jsonpatch.ErrConflict))
+			Ω(result.Conflicts).Should(HaveLen(1))
+			_, err = jsonpatch.CreateThreeWayJSONPatch(B{Str: "ours"}, B{Str: "theirs"}, B{Str: "original"}, jsonpatch.WithConflictResolver(jsonpatch.ResolveFail))
+			Ω(err).Should(MatchError(jsonpatch.ErrConflict))
+		})
+	})
 	Context("CreateThreeWayJSONPatch_fuzzy", func() {
 		var (
 			current  G
@@ -543,6 +1103,72 @@ var _ = Describe("JSONPatch", func() {
 			})
 		}
 	})
+	Context("CreateThreeWayJSONPatch_fuzzy_original", func() {
+		var (
+			original G
+			current  G
+			modified G
+		)
+		BeforeEach(func() {
+			original = G{}
+			err := faker.FakeData(&original)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			current = G{}
+			err = faker.FakeData(&current)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			modified = G{}
+			err = faker.FakeData(&modified)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		for i := 0; i < 10; i++ {
+			It("fuzzy "+strconv.Itoa(i), func() {
+				// the elements are distinct (and the original slices have at least two of them), hence their alignment
+				// and the merged slices are known
+				ints := func(n, offset int) []int {
+					values := make([]int, n)
+					for j := range values {
+						values[j] = 3*(j+1) + offset
+					}
+					return values
+				}
+				strs := func(n int, prefix string) []string {
+					values := make([]string, n)
+					for j := range values {
+						values[j] = prefix + strconv.Itoa(j)
+					}
+					return values
+				}
+				original.D.IntSlice = ints(len(original.D.IntSlice)+2, 0)
+				addedCurrentInts, addedModifiedInts := ints(len(current.D.IntSlice), 1), ints(len(modified.D.IntSlice), 2)
+				original.D.StringSlice = strs(len(original.D.StringSlice)+2, "o")
+				addedCurrentStrs, addedModifiedStrs := strs(len(current.D.StringSlice), "c"), strs(len(modified.D.StringSlice), "m")
+
+				// derive the current and modified JSON from the original to get overlapping slices
+				h := len(original.D.IntSlice) / 2
+				current.D.IntSlice = slices.Concat([]int{1}, original.D.IntSlice, addedCurrentInts)
+				modified.D.IntSlice = slices.Concat(original.D.IntSlice[h:], addedModifiedInts)
+				expectedInts := slices.Concat([]int{1}, original.D.IntSlice[h:], addedModifiedInts, addedCurrentInts)
+				h = len(original.D.StringSlice) / 2
+				current.D.StringSlice = slices.Concat(original.D.StringSlice[1:], addedCurrentStrs)
+				modified.D.StringSlice = slices.Concat(addedModifiedStrs, original.D.StringSlice[:h])
+				expectedStrs := slices.Concat(addedModifiedStrs, original.D.StringSlice[1:h], addedCurrentStrs)
+
+				patched := testThreeWayPatchApply(modified, current, original).(G)
+				Ω(slices.Equal(patched.D.IntSlice, expectedInts)).Should(BeTrue(), "merged: %v expected: %v", patched.D.IntSlice, expectedInts)
+				Ω(slices.Equal(patched.D.StringSlice, expectedStrs)).Should(BeTrue(), "merged: %v expected: %v", patched.D.StringSlice, expectedStrs)
+
+				// ints aren't unique, hence their slice order can't be ignored
+				original.D.IntSlice, current.D.IntSlice, modified.D.IntSlice = nil, nil, nil
+				testThreeWayPatchFixpoint(modified, current, original, jsonpatch.IgnoreSliceOrderWithPattern([]jsonpatch.IgnorePattern{
+					{"/d/strs", ""}, {"/d/floats", ""},
+					{"/d/ptr", "str"}, {"/d/structs", "str"}, {"/d/ptrWithKey", "str"}, {"/d/structsWithKey", "str"},
+				}))
+			})
+		}
+	})
 })
 
 func testPatch(modified, current interface{}) {
@@ -593,6 +1219,37 @@ func testThreeWayPatch(modified, current interface{}) {
 	Ω(patchedJSON).Should(MatchJSON(modifiedJSON))
 }
 
+func testThreeWayPatchApply(modified, current, original interface{}, options ...jsonpatch.Option) interface{} {
+	currentJSON, err := json.Marshal(current)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	list, err := jsonpatch.CreateThreeWayJSONPatch(modified, current, original, options...)
+	Ω(err).ShouldNot(HaveOccurred())
+	if list.Empty() {
+		return current
+	}
+
+	// the patch must be applicable to the current JSON
+	jsonPatch, err := jsonpatch2.DecodePatch(list.Raw())
+	Ω(err).ShouldNot(HaveOccurred())
+	patchedJSON, err := jsonPatch.Apply(currentJSON)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	patched := reflect.New(reflect.TypeOf(current))
+	Ω(json.Unmarshal(patchedJSON, patched.Interface())).Should(Succeed())
+
+	return patched.Elem().Interface()
+}
+
+func testThreeWayPatchFixpoint(modified, current, original interface{}, options ...jsonpatch.Option) {
+	patched := testThreeWayPatchApply(modified, current, original, options...)
+
+	// patching the patched JSON once more must not change it anymore
+	list, err := jsonpatch.CreateThreeWayJSONPatch(modified, patched, original, options...)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(list.List()).Should(BeEmpty())
+}
+
 func testPatchWithExpected(modified, current, expected interface{}, options ...jsonpatch.Option) {
 	currentJSON, err := json.Marshal(current)
 	Ω(err).ShouldNot(HaveOccurred())
@@ -619,7 +1276,7 @@ func testPatchWithExpected(modified, current, expected interface{}, options ...j
 	Ω(patchedJSON).Should(MatchJSON(expectedJSON))
 }
 
-func testThreeWayPatchWithExpected(modified, current, original, expected interface{}) {
+func testThreeWayPatchWithExpected(modified, current, original, expected interface{}, options ...jsonpatch.Option) {
 	currentJSON, err := json.Marshal(current)
 	Ω(err).ShouldNot(HaveOccurred())
 	_, err = json.Marshal(modified)
@@ -627,7 +1284,7 @@ func testThreeWayPatchWithExpected(modified, current, original, expected interfa
 	expectedJSON, err := json.Marshal(expected)
 	Ω(err).ShouldNot(HaveOccurred())
 
-	list, err := jsonpatch.CreateThreeWayJSONPatch(modified, current, original)
+	list, err := jsonpatch.CreateThreeWayJSONPatch(modified, current, original, options...)
 	Ω(err).ShouldNot(HaveOccurred())
 	if list.Empty() {
 		Ω(currentJSON).Should(MatchJSON(expectedJSON))
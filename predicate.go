@@ -1,5 +1,9 @@
 package jsonpatch
 
+import (
+	"context"
+)
+
 // Predicate filters patches
 type Predicate interface {
 	// Add returns true if the object should not be added in the patch
@@ -12,6 +16,15 @@ type Predicate interface {
 	Replace(pointer JSONPointer, modified, current interface{}) bool
 }
 
+// ContextPredicate is a Predicate which is bound to the context of the patch creation (see CreateJSONPatchContext) by
+// WithPredicate, the walker uses the returned Predicate instead
+type ContextPredicate interface {
+	Predicate
+
+	// WithContext returns the Predicate which is used for the patch creation with the context
+	WithContext(ctx context.Context) Predicate
+}
+
 // Funcs is a function that implements Predicate
 type Funcs struct {
 	// Add returns true if the object should not be added in the patch
@@ -50,3 +63,312 @@ func (p Funcs) Replace(pointer JSONPointer, modified, current interface{}) bool
 
 	return true
 }
+
+// And returns a Predicate which only accepts a patch if all predicates accept it
+func And(predicates ...Predicate) Predicate {
+	return descendFuncs{Funcs: Funcs{
+		AddFunc: func(pointer JSONPointer, modified interface{}) bool {
+			for _, predicate := range predicates {
+				if !predicate.Add(pointer, modified) {
+					return false
+				}
+			}
+			return true
+		},
+		RemoveFunc: func(pointer JSONPointer, current interface{}) bool {
+			for _, predicate := range predicates {
+				if !predicate.Remove(pointer, current) {
+					return false
+				}
+			}
+			return true
+		},
+		ReplaceFunc: func(pointer JSONPointer, modified, current interface{}) bool {
+			for _, predicate := range predicates {
+				if !predicate.Replace(pointer, modified, current) {
+					return false
+				}
+			}
+			return true
+		},
+	}, descendFunc: func(pointer JSONPointer, modified, current interface{}) bool {
+		for _, predicate := range predicates {
+			if !descend(predicate, pointer, modified, current) {
+				return false
+			}
+		}
+		return true
+	}}
+}
+
+// Or returns a Predicate which accepts a patch if one of the predicates accepts it
+func Or(predicates ...Predicate) Predicate {
+	return descendFuncs{Funcs: Funcs{
+		AddFunc: func(pointer JSONPointer, modified interface{}) bool {
+			for _, predicate := range predicates {
+				if predicate.Add(pointer, modified) {
+					return true
+				}
+			}
+			return false
+		},
+		RemoveFunc: func(pointer JSONPointer, current interface{}) bool {
+			for _, predicate := range predicates {
+				if predicate.Remove(pointer, current) {
+					return true
+				}
+			}
+			return false
+		},
+		ReplaceFunc: func(pointer JSONPointer, modified, current interface{}) bool {
+			for _, predicate := range predicates {
+				if predicate.Replace(pointer, modified, current) {
+					return true
+				}
+			}
+			return false
+		},
+	}, descendFunc: func(pointer JSONPointer, modified, current interface{}) bool {
+		for _, predicate := range predicates {
+			if descend(predicate, pointer, modified, current) {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// Not returns a Predicate which accepts a patch if the predicate doesn't accept it.
+// NOTE: the walker always walks into struct and slice values, only their patches are filtered
+func Not(predicate Predicate) Predicate {
+	return descendFuncs{Funcs: Funcs{
+		AddFunc: func(pointer JSONPointer, modified interface{}) bool {
+			return !predicate.Add(pointer, modified)
+		},
+		RemoveFunc: func(pointer JSONPointer, current interface{}) bool {
+			return !predicate.Remove(pointer, current)
+		},
+		ReplaceFunc: func(pointer JSONPointer, modified, current interface{}) bool {
+			return !predicate.Replace(pointer, modified, current)
+		},
+	}, descendFunc: func(JSONPointer, interface{}, interface{}) bool {
+		return true
+	}}
+}
+
+// OnlyPaths returns a Predicate which only accepts patches of values which paths match one of the patterns (e.g.
+// "/spec" or "/data/*") or which are children of them. Parents of the patterns are accepted as well, in order to walk
+// into them.
+func OnlyPaths(patterns ...string) Predicate {
+	accept := func(pointer JSONPointer) bool {
+		return matchPaths(pointer, patterns) || parentOfPaths(pointer, patterns)
+	}
+
+	return Funcs{
+		AddFunc: func(pointer JSONPointer, _ interface{}) bool {
+			return accept(pointer)
+		},
+		RemoveFunc: func(pointer JSONPointer, _ interface{}) bool {
+			return accept(pointer)
+		},
+		ReplaceFunc: func(pointer JSONPointer, _, _ interface{}) bool {
+			return accept(pointer)
+		},
+	}
+}
+
+// ExcludePaths returns a Predicate which drops the patches of values which paths match one of the patterns (e.g.
+// "/status" or "/metadata/annotations/*") or which are children of them
+func ExcludePaths(patterns ...string) Predicate {
+	return Funcs{
+		AddFunc: func(pointer JSONPointer, _ interface{}) bool {
+			return !matchPaths(pointer, patterns)
+		},
+		RemoveFunc: func(pointer JSONPointer, _ interface{}) bool {
+			return !matchPaths(pointer, patterns)
+		},
+		ReplaceFunc: func(pointer JSONPointer, _, _ interface{}) bool {
+			return !matchPaths(pointer, patterns)
+		},
+	}
+}
+
+// OnlyOps returns a Predicate which only accepts patches with one of the operations ('add', 'remove' or 'replace').
+// NOTE: the walker always walks into struct and slice values, only their patches are filtered
+func OnlyOps(operations ...string) Predicate {
+	accept := func(operation string) bool {
+		for _, op := range operations {
+			if op == operation {
+				return true
+			}
+		}
+		return false
+	}
+
+	return descendFuncs{Funcs: Funcs{
+		AddFunc: func(JSONPointer, interface{}) bool {
+			return accept("add")
+		},
+		RemoveFunc: func(JSONPointer, interface{}) bool {
+			return accept("remove")
+		},
+		ReplaceFunc: func(JSONPointer, interface{}, interface{}) bool {
+			return accept("replace")
+		},
+	}, descendFunc: func(JSONPointer, interface{}, interface{}) bool {
+		return true
+	}}
+}
+
+// descender is implemented by predicates which decide whether the walker walks into a struct or slice value
+// independently of a 'replace' of the value
+type descender interface {
+	descend(pointer JSONPointer, modified, current interface{}) bool
+}
+
+// descendFuncs is a Funcs predicate which decides with a separate function whether the walker walks into a value
+type descendFuncs struct {
+	Funcs
+	descendFunc func(pointer JSONPointer, modified, current interface{}) bool
+}
+
+// descend implements descender
+func (p descendFuncs) descend(pointer JSONPointer, modified, current interface{}) bool {
+	return p.descendFunc(pointer, modified, current)
+}
+
+// descend returns true if the walker walks into the struct or slice value, predicates which don't implement descender
+// decide with 'replace'
+func descend(predicate Predicate, pointer JSONPointer, modified, current interface{}) bool {
+	if d, ok := predicate.(descender); ok {
+		return d.descend(pointer, modified, current)
+	}
+
+	return predicate.Replace(pointer, modified, current)
+}
+
+// matchPaths reports whether the pointer or one of its parents matches one of the patterns, a trailing wildcard
+// matches the children of a value, but not the value itself
+func matchPaths(pointer JSONPointer, patterns []string) bool {
+	for _, pattern := range patterns {
+		for j := len(ParseJSONPointer(pattern)); j <= len(pointer); j++ {
+			if pointer[:j].Match(pattern) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// parentOfPaths reports whether the pointer is a parent of a value which path matches one of the patterns
+func parentOfPaths(pointer JSONPointer, patterns []string) bool {
+	for _, pattern := range patterns {
+		elements := ParseJSONPointer(pattern)
+		if len(pointer) >= len(elements) {
+			continue
+		}
+
+		parent := true
+		for i := range pointer {
+			if elements[i] != wildcard && elements[i] != pointer[i] {
+				parent = false
+				break
+			}
+		}
+		if parent {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ErrorPredicate filters patches like a Predicate, but can abort the patch creation with an error (e.g. if a patch is
+// invalid). The error is returned by the patch creation together with the pointer.
+type ErrorPredicate interface {
+	// Add returns true if the object should be added in the patch
+	Add(pointer JSONPointer, modified interface{}) (bool, error)
+
+	// Remove returns true if the object should be deleted in the patch
+	Remove(pointer JSONPointer, current interface{}) (bool, error)
+
+	// Replace returns true if the objects should be updated in the patch - returning false will stop the recursive processing of those objects
+	Replace(pointer JSONPointer, modified, current interface{}) (bool, error)
+}
+
+// ErrorFuncs is a function that implements ErrorPredicate
+type ErrorFuncs struct {
+	// Add returns true if the object should be added in the patch
+	AddFunc func(pointer JSONPointer, modified interface{}) (bool, error)
+
+	// Remove returns true if the object should be deleted in the patch
+	RemoveFunc func(pointer JSONPointer, current interface{}) (bool, error)
+
+	// Replace returns true if the objects should be updated in the patch
+	ReplaceFunc func(pointer JSONPointer, modified, current interface{}) (bool, error)
+}
+
+// Add implements ErrorPredicate
+func (p ErrorFuncs) Add(pointer JSONPointer, modified interface{}) (bool, error) {
+	if p.AddFunc != nil {
+		return p.AddFunc(pointer, modified)
+	}
+
+	return true, nil
+}
+
+// Remove implements ErrorPredicate
+func (p ErrorFuncs) Remove(pointer JSONPointer, current interface{}) (bool, error) {
+	if p.RemoveFunc != nil {
+		return p.RemoveFunc(pointer, current)
+	}
+
+	return true, nil
+}
+
+// Replace implements ErrorPredicate
+func (p ErrorFuncs) Replace(pointer JSONPointer, modified, current interface{}) (bool, error) {
+	if p.ReplaceFunc != nil {
+		return p.ReplaceFunc(pointer, modified, current)
+	}
+
+	return true, nil
+}
+
+// errorPredicate adapts an ErrorPredicate to a Predicate which records the errors in the walker
+type errorPredicate struct {
+	predicate ErrorPredicate
+	walker    *walker
+}
+
+// Add implements Predicate
+func (p *errorPredicate) Add(pointer JSONPointer, modified interface{}) bool {
+	ok, err := p.predicate.Add(pointer, modified)
+
+	return p.check(pointer, ok, err)
+}
+
+// Remove implements Predicate
+func (p *errorPredicate) Remove(pointer JSONPointer, current interface{}) bool {
+	ok, err := p.predicate.Remove(pointer, current)
+
+	return p.check(pointer, ok, err)
+}
+
+// Replace implements Predicate
+func (p *errorPredicate) Replace(pointer JSONPointer, modified, current interface{}) bool {
+	ok, err := p.predicate.Replace(pointer, modified, current)
+
+	return p.check(pointer, ok, err)
+}
+
+// check records the error and filters the patch if there is one
+func (p *errorPredicate) check(pointer JSONPointer, ok bool, err error) bool {
+	if err != nil {
+		p.walker.fail(pointer, err)
+		return false
+	}
+
+	return ok
+}
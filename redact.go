@@ -0,0 +1,157 @@
+package jsonpatch
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strconv"
+)
+
+// DefaultPlaceholder replaces sensitive values if the Redactor has no placeholder
+const DefaultPlaceholder = "[REDACTED]"
+
+// Redactor redacts copies of the patches, e.g. in order to log them. The values which paths match one of the patterns,
+// and the values of struct fields tagged with `jsonpatch:"sensitive"`, are replaced by a placeholder or by their HMAC.
+// The patch created with the Redactor isn't redacted and can still be applied.
+type Redactor struct {
+	// Patterns are JSONPointer patterns of sensitive values (e.g. "/data/*" or "/spec/password")
+	Patterns []string
+
+	// Placeholder replaces the sensitive values, the DefaultPlaceholder is used if it is empty
+	Placeholder string
+
+	// Key replaces the sensitive values by the HMAC-SHA256 of their JSON encoding with the key instead of the
+	// placeholder, e.g. in order to detect changes of sensitive values without revealing them
+	Key []byte
+}
+
+// redact returns the replacement of a sensitive value
+func (r *Redactor) redact(value interface{}) (interface{}, error) {
+	if len(r.Key) == 0 {
+		if r.Placeholder == "" {
+			return DefaultPlaceholder, nil
+		}
+		return r.Placeholder, nil
+	}
+
+	// the value is decoded first in order to hash the same encoding for structs and maps (sorted keys)
+	doc, err := decode(value)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, r.Key)
+	mac.Write(raw)
+
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// redact passes the redacted copies of the patches to the sink of the Redactor
+func (w *walker) redact(patches []JSONPatch) error {
+	if w.redactor == nil || w.redactedSink == nil {
+		// without a sink there is nothing to redact for
+		return nil
+	}
+
+	for _, patch := range patches {
+		if patch.Value != nil {
+			pointer := ParseJSONPointer(patch.Path)
+			t, sensitive, err := w.sensitiveAt(reflect.TypeOf(w.modifiedRoot), pointer)
+			if err != nil {
+				return err
+			}
+			if sensitive || matchPaths(pointer, w.redactor.Patterns) {
+				if patch.Value, err = w.redactor.redact(patch.Value); err != nil {
+					return err
+				}
+			} else {
+				doc, err := decode(patch.Value)
+				if err != nil {
+					return err
+				}
+				if redacted, ok, err := w.redactDocument(doc, t, pointer); err != nil {
+					return err
+				} else if ok {
+					patch.Value = redacted
+				}
+			}
+		}
+		if err := w.redactedSink(patch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sensitiveAt returns the type of the value at the pointer, starting with the type t at the prefix, and whether the
+// value or one of its parents is a struct field tagged as sensitive
+func (w *walker) sensitiveAt(t reflect.Type, pointer JSONPointer) (reflect.Type, bool, error) {
+	if len(pointer) < len(w.prefix) {
+		return nil, false, nil
+	}
+
+	sensitive := false
+	for j := len(w.prefix); j < len(pointer) && t != nil; j++ {
+		childType, childSensitive, err := w.childOf(t, unescape(pointer[j]), pointer[:j+1])
+		if err != nil {
+			return nil, false, err
+		}
+		t, sensitive = childType, sensitive || childSensitive
+	}
+
+	return t, sensitive, nil
+}
+
+// childOf returns the type of the child of a value of the type t and whether it is a struct field tagged as sensitive
+func (w *walker) childOf(t reflect.Type, key string, pointer JSONPointer) (reflect.Type, bool, error) {
+	if elemType := elemTypeOf(t); elemType != nil {
+		return elemType, false, nil
+	}
+	childType, options, err := w.schemaOf(t, key, pointer)
+
+	return childType, options.sensitive, err
+}
+
+// redactDocument replaces the sensitive values of a generic JSON document of the type t at the pointer and returns true
+// if any value is replaced
+func (w *walker) redactDocument(doc interface{}, t reflect.Type, pointer JSONPointer) (interface{}, bool, error) {
+	redacted := false
+	redactChild := func(value interface{}, key string, child JSONPointer) (interface{}, error) {
+		childType, sensitive, err := w.childOf(t, key, child)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil && (sensitive || matchPaths(child, w.redactor.Patterns)) {
+			redacted = true
+			return w.redactor.redact(value)
+		}
+		value, ok, err := w.redactDocument(value, childType, child)
+		redacted = redacted || ok
+
+		return value, err
+	}
+
+	var err error
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if v[key], err = redactChild(value, key, pointer.Add(key)); err != nil {
+				return nil, false, err
+			}
+		}
+	case []interface{}:
+		for j, value := range v {
+			if v[j], err = redactChild(value, strconv.Itoa(j), pointer.Add(strconv.Itoa(j))); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+
+	return doc, redacted, nil
+}
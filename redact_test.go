@@ -0,0 +1,129 @@
+package jsonpatch_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+type P struct {
+	Name     string            `json:"name"`
+	Password string            `json:"password" jsonpatch:"sensitive"`
+	Data     map[string]string `json:"data,omitempty"`
+	Users    []Q               `json:"users"`
+}
+
+type Q struct {
+	Name  string `json:"name"`
+	Token string `json:"token" jsonpatch:"sensitive"`
+}
+
+var _ = Describe("Redactor", func() {
+	var (
+		modified P
+		current  P
+		redacted []jsonpatch.JSONPatch
+		sink     func(jsonpatch.JSONPatch) error
+	)
+	BeforeEach(func() {
+		redacted = nil
+		sink = func(patch jsonpatch.JSONPatch) error {
+			redacted = append(redacted, patch)
+			return nil
+		}
+		modified = P{Name: "new", Password: "secret", Data: map[string]string{"key": "value"}, Users: []Q{{Name: "a", Token: "x"}, {Name: "b", Token: "y"}}}
+		current = P{Name: "old", Password: "password", Users: []Q{{Name: "a", Token: "z"}}}
+	})
+
+	Context("WithRedactor", func() {
+		It("should redact sensitive values", func() {
+			redactor := jsonpatch.Redactor{Patterns: []string{"/data"}}
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithRedactor(redactor, sink))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(ConsistOf(
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/name", Value: "new"},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/password", Value: "secret"},
+				jsonpatch.JSONPatch{Operation: "add", Path: "/data", Value: modified.Data},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/users/0/token", Value: "x"},
+				jsonpatch.JSONPatch{Operation: "add", Path: "/users/1", Value: modified.Users[1]},
+			))
+			Ω(redacted).Should(ConsistOf(
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/name", Value: "new"},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/password", Value: "[REDACTED]"},
+				jsonpatch.JSONPatch{Operation: "add", Path: "/data", Value: "[REDACTED]"},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/users/0/token", Value: "[REDACTED]"},
+				jsonpatch.JSONPatch{Operation: "add", Path: "/users/1", Value: map[string]interface{}{"name": "b", "token": "[REDACTED]"}},
+			))
+		})
+		It("should redact values matching patterns within values", func() {
+			redactor := jsonpatch.Redactor{Patterns: []string{"/data/*", "/users/*/name"}, Placeholder: "***"}
+			_, err := jsonpatch.CreateThreeWayJSONPatch(modified, current, current, jsonpatch.WithRedactor(redactor, sink))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(redacted).Should(ConsistOf(
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/name", Value: "new"},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/password", Value: "***"},
+				jsonpatch.JSONPatch{Operation: "add", Path: "/data", Value: map[string]interface{}{"key": "***"}},
+				jsonpatch.JSONPatch{Operation: "replace", Path: "/users/0/token", Value: "***"},
+				jsonpatch.JSONPatch{Operation: "add", Path: "/users/1", Value: map[string]interface{}{"name": "***", "token": "***"}},
+			))
+		})
+		It("should hash sensitive values with the key", func() {
+			redactor := jsonpatch.Redactor{Key: []byte("key")}
+			_, err := jsonpatch.CreateJSONPatch(P{Password: "secret"}, P{}, jsonpatch.WithRedactor(redactor, sink))
+			Ω(err).ShouldNot(HaveOccurred())
+			mac := hmac.New(sha256.New, []byte("key"))
+			mac.Write([]byte(`"secret"`))
+			Ω(redacted).Should(Equal([]jsonpatch.JSONPatch{
+				{Operation: "add", Path: "/password", Value: "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))},
+			}))
+		})
+		It("should fail with the error of the sink", func() {
+			errSink := errors.New("sink")
+			_, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithRedactor(jsonpatch.Redactor{}, func(jsonpatch.JSONPatch) error {
+				return errSink
+			}))
+			Ω(err).Should(MatchError(errSink))
+		})
+		It("should not redact without a sink", func() {
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithRedactor(jsonpatch.Redactor{Patterns: []string{"/data"}}, nil))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(list.List()).Should(ContainElement(jsonpatch.JSONPatch{Operation: "replace", Path: "/password", Value: "secret"}))
+
+			var patches []jsonpatch.JSONPatch
+			Ω(jsonpatch.WalkPatch(func(patch jsonpatch.JSONPatch) error {
+				patches = append(patches, patch)
+				return nil
+			}, modified, current, jsonpatch.WithRedactor(jsonpatch.Redactor{}, nil))).Should(Succeed())
+			Ω(patches).Should(ConsistOf(list.List()))
+		})
+		It("should redact concurrently created patches", func() {
+			var (
+				modified = P{Data: map[string]string{}}
+				current  = P{Data: map[string]string{}}
+			)
+			for j := 0; j < 100; j++ {
+				modified.Data[strconv.Itoa(j)] = "new"
+				current.Data[strconv.Itoa(j)] = "old"
+			}
+			list, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithConcurrency(4), jsonpatch.WithRedactor(jsonpatch.Redactor{Patterns: []string{"/data/*"}}, sink))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(redacted).Should(HaveLen(list.Len()))
+			for j, patch := range list.List() {
+				Ω(redacted[j]).Should(Equal(jsonpatch.JSONPatch{Operation: patch.Operation, Path: patch.Path, Value: "[REDACTED]"}))
+			}
+		})
+		It("should redact values with a prefix", func() {
+			redactor := jsonpatch.Redactor{}
+			_, err := jsonpatch.CreateJSONPatch(modified, current, jsonpatch.WithPrefix(jsonpatch.ParseJSONPointer("/spec")), jsonpatch.WithRedactor(redactor, sink))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(redacted).Should(ContainElement(jsonpatch.JSONPatch{Operation: "replace", Path: "/spec/password", Value: "[REDACTED]"}))
+		})
+	})
+})
@@ -0,0 +1,538 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+const (
+	// directivePatch is the key of the '$patch' directive which deletes list elements or replaces maps
+	directivePatch = "$patch"
+	// directiveRetainKeys is the key of the '$retainKeys' directive which clears all keys which aren't listed
+	directiveRetainKeys = "$retainKeys"
+	// directiveSetElementOrder is the key prefix of the '$setElementOrder' directive which specifies the list order
+	directiveSetElementOrder = "$setElementOrder/"
+	// directiveDeleteFromPrimitiveList is the key prefix of the '$deleteFromPrimitiveList' directive
+	directiveDeleteFromPrimitiveList = "$deleteFromPrimitiveList/"
+)
+
+// CreateStrategicMergePatch compares two JSON objects and creates a Kubernetes strategic merge patch (i.e. the content
+// type 'application/strategic-merge-patch+json'). The objects are walked like by CreateJSONPatch and the created patches
+// are folded into the strategic merge patch, therefore all options (e.g. predicates, handlers, deciders, immutable
+// values or the max depth) are supported, except for concurrency. The redacted copies of the folded patches are passed
+// to the sink of the Redactor, the strategic merge patch itself isn't redacted. Lists are replaced as a whole, unless they are
+// unordered by a struct tag (e.g. the Kubernetes 'merge' patch strategy) or by IgnoreSliceOrderWithPattern: the elements
+// of such lists are merged by their merge key (or by their value for lists of primitives). Removed elements are deleted
+// with the '$patch: delete' or '$deleteFromPrimitiveList' directive and the order of the modified list is kept with the
+// '$setElementOrder' directive. Objects with the 'retainKeys' strategy list their keys with the '$retainKeys' directive
+// and objects which are replaced as a whole (e.g. atomic objects) are replaced with the '$patch: replace' directive.
+func CreateStrategicMergePatch(modified, current interface{}, options ...Option) ([]byte, error) {
+	w := newWalker(options...)
+	w.modifiedRoot, w.currentRoot = modified, current
+	// the patches are folded in the order of the walk
+	w.concurrency = 0
+
+	m, err := decode(modified)
+	if err != nil {
+		return nil, err
+	}
+	c, err := decode(current)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := m.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("strategic merge patches are only supported for objects at: %s", JSONPointer(w.prefix))
+	}
+	if _, ok := c.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("strategic merge patches are only supported for objects at: %s", JSONPointer(w.prefix))
+	}
+
+	s := &strategicHandler{
+		handler:  w.handler,
+		walker:   w,
+		modified: m,
+		current:  c,
+		t:        reflect.TypeOf(modified),
+		patch:    strategicObject{},
+		lists:    map[string]*strategicList{},
+	}
+	w.handler = s
+	w.unordered = s.unordered
+
+	if err := w.walk(reflect.ValueOf(modified), reflect.ValueOf(current), reflect.Value{}, w.prefix); err != nil {
+		return nil, err
+	}
+	if err := w.err(); err != nil {
+		return nil, err
+	}
+	for _, list := range s.lists {
+		list.fold()
+	}
+
+	return json.Marshal(s.patch)
+}
+
+// strategicObject is an object of a strategic merge patch which patches the members of an object one by one, unlike
+// the plain objects of the patched values which are set as a whole
+type strategicObject map[string]interface{}
+
+// strategicHandler folds the patches of its Handler into a strategic merge patch
+type strategicHandler struct {
+	handler  Handler
+	walker   *walker
+	modified interface{}
+	current  interface{}
+	t        reflect.Type
+	patch    strategicObject
+	lists    map[string]*strategicList
+}
+
+// Add implements Handler
+func (s *strategicHandler) Add(pointer JSONPointer, modified interface{}) []JSONPatch {
+	s.foldAll(pointer, s.handler.Add(pointer, modified))
+	return nil
+}
+
+// Remove implements Handler
+func (s *strategicHandler) Remove(pointer JSONPointer, current interface{}) []JSONPatch {
+	s.foldAll(pointer, s.handler.Remove(pointer, current))
+	return nil
+}
+
+// Replace implements Handler
+func (s *strategicHandler) Replace(pointer JSONPointer, modified, current interface{}) []JSONPatch {
+	s.foldAll(pointer, s.handler.Replace(pointer, modified, current))
+	return nil
+}
+
+// foldAll folds the patches into the strategic merge patch and records the error if one of them can't be folded, the
+// redacted copies of the patches are passed to the sink of the Redactor before
+func (s *strategicHandler) foldAll(pointer JSONPointer, patches []JSONPatch) {
+	if err := s.walker.redact(patches); err != nil {
+		s.walker.fail(pointer, err)
+		return
+	}
+	for _, patch := range patches {
+		if err := s.fold(patch); err != nil {
+			s.walker.fail(pointer, err)
+			return
+		}
+	}
+}
+
+// unordered is called by the walker for every unordered list before its elements are walked, in order to keep track of
+// the elements and their order
+func (s *strategicHandler) unordered(pointer JSONPointer, modified, current reflect.Value) {
+	ignore, _ := s.walker.ignoredSliceOf(pointer)
+	list := &strategicList{key: ignore.JSONField}
+	if m, err := decode(modified.Interface()); err == nil {
+		list.modified, _ = m.([]interface{})
+	}
+	if c, err := decode(current.Interface()); err == nil {
+		list.current, _ = c.([]interface{})
+	}
+	list.index()
+	s.lists[pointer.String()] = list
+
+	if list.reordered() {
+		if err := s.fold(JSONPatch{Path: pointer.String()}); err != nil {
+			s.walker.fail(pointer, err)
+		}
+	}
+}
+
+// fold folds a patch into the strategic merge patch, a patch without an operation only keeps the order of an unordered
+// list. The objects of the patch are created along its path, members which are missing in the current or modified object
+// and lists which aren't merged are set as a whole.
+func (s *strategicHandler) fold(patch JSONPatch) error {
+	pointer := ParseJSONPointer(patch.Path)
+	if len(pointer) < len(s.walker.prefix) || !slices.Equal(pointer[:len(s.walker.prefix)], s.walker.prefix) {
+		return fmt.Errorf("path %s is not part of the strategic merge patch", patch.Path)
+	}
+
+	var (
+		object   = s.patch
+		current  = s.current
+		modified = s.modified
+		t        = s.t
+	)
+	if len(pointer) == len(s.walker.prefix) {
+		value, err := decode(patch.Value)
+		if v, ok := value.(map[string]interface{}); ok && err == nil && (patch.Operation == "add" || patch.Operation == "replace") {
+			s.patch = replacedObject(v)
+			return nil
+		}
+		return fmt.Errorf("strategic merge patches are only supported for objects")
+	}
+
+	for n := len(s.walker.prefix); n < len(pointer); n++ {
+		key := unescape(pointer[n])
+		childType, options, err := s.walker.schemaOf(t, key, pointer[:n+1])
+		if err != nil {
+			return err
+		}
+		c, _ := current.(map[string]interface{})[key]
+		m, _ := modified.(map[string]interface{})[key]
+		if n == len(pointer)-1 {
+			return s.set(object, key, patch, c, options, pointer)
+		}
+
+		switch {
+		case m == nil:
+			// members which are null or missing in the modified object are deleted
+			object[key] = nil
+			return nil
+		case c == nil:
+			object[key] = m
+			return nil
+		}
+
+		switch mValue := m.(type) {
+		case map[string]interface{}:
+			child, ok := object[key].(strategicObject)
+			if !ok {
+				if _, ok := object[key]; ok {
+					// the member is already set as a whole
+					return nil
+				}
+				child = strategicObject{}
+				if options.retainKeys {
+					child[directiveRetainKeys] = retainedKeys(mValue)
+				}
+				object[key] = child
+			}
+			object, current, modified, t = child, c, m, childType
+		case []interface{}:
+			list, ok := s.lists[pointer[:n+1].String()]
+			if !ok {
+				// lists which aren't merged are replaced as a whole
+				whole(object, key, mValue, c, options)
+				return nil
+			}
+			list.object, list.field = object, key
+
+			j, err := strconv.Atoi(pointer[n+1])
+			if err != nil || j < 0 {
+				return fmt.Errorf("invalid index %s of the list %s", pointer[n+1], pointer[:n+1])
+			}
+			if n+1 == len(pointer)-1 {
+				return list.set(j, patch)
+			}
+			if object, current, modified, err = list.element(j); err != nil {
+				return err
+			}
+			t = elemTypeOf(childType)
+			n++
+		default:
+			object[key] = m
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// set folds the patch of the member of an object into the strategic merge patch
+func (s *strategicHandler) set(object strategicObject, key string, patch JSONPatch, current interface{}, options fieldOptions, pointer JSONPointer) error {
+	switch patch.Operation {
+	case "":
+		if list, ok := s.lists[pointer.String()]; ok {
+			list.object, list.field = object, key
+		}
+	case "remove":
+		object[key] = nil
+	case "add", "replace":
+		value, err := decode(patch.Value)
+		if err != nil {
+			return err
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if _, ok := current.(map[string]interface{}); ok && patch.Operation == "replace" {
+				object[key] = replacedObject(v)
+			} else {
+				object[key] = v
+			}
+		case []interface{}:
+			whole(object, key, v, current, options)
+		default:
+			object[key] = v
+		}
+	default:
+		return fmt.Errorf("operation %s is not supported by strategic merge patches", patch.Operation)
+	}
+
+	return nil
+}
+
+// replacedObject returns a copy of the object with the '$patch: replace' directive
+func replacedObject(object map[string]interface{}) strategicObject {
+	replace := strategicObject{directivePatch: "replace"}
+	for k, v := range object {
+		replace[k] = v
+	}
+
+	return replace
+}
+
+// whole sets a list as a whole, the elements of merged lists are replaced by the '$patch: replace' directive or, for
+// lists of primitives, by deleting the elements which aren't part of the modified list
+func whole(object strategicObject, field string, modified []interface{}, current interface{}, options fieldOptions) {
+	if !options.unordered {
+		object[field] = modified
+		return
+	}
+	if options.key != "" {
+		object[field] = append(clone(modified).([]interface{}), map[string]interface{}{directivePatch: "replace"})
+		return
+	}
+
+	kept := map[string]bool{}
+	for _, element := range modified {
+		k, _ := keyOf(element, "")
+		kept[k] = true
+	}
+	var deleted []interface{}
+	for _, element := range asList(current) {
+		if k, _ := keyOf(element, ""); !kept[k] {
+			deleted = append(deleted, element)
+		}
+	}
+	object[field] = modified
+	if len(deleted) > 0 {
+		object[directiveDeleteFromPrimitiveList+field] = deleted
+	}
+	object[directiveSetElementOrder+field] = modified
+}
+
+// asList returns the value if it is a list or nil
+func asList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+// strategicList is the strategic merge patch of a list which elements are merged by their merge key (or by their value
+// for lists of primitives)
+type strategicList struct {
+	key         string
+	modified    []interface{}
+	current     []interface{}
+	modifiedIdx map[string]int
+	object      strategicObject
+	field       string
+	added       map[string]interface{}
+	patched     map[string]strategicObject
+	removed     map[string]bool
+}
+
+// index maps the merge keys of the modified elements to their index
+func (l *strategicList) index() {
+	l.modifiedIdx = map[string]int{}
+	for j, element := range l.modified {
+		if k, ok := keyOf(element, l.key); ok {
+			l.modifiedIdx[k] = j
+		}
+	}
+	l.added = map[string]interface{}{}
+	l.patched = map[string]strategicObject{}
+	l.removed = map[string]bool{}
+}
+
+// reordered returns true if the elements which are part of both lists are in a different order
+func (l *strategicList) reordered() bool {
+	var common []int
+	for _, element := range l.current {
+		k, _ := keyOf(element, l.key)
+		if j, ok := l.modifiedIdx[k]; ok {
+			common = append(common, j)
+		}
+	}
+
+	return !sort.IntsAreSorted(common)
+}
+
+// set folds the patch of the element at the index j of the current list (or the index of an added element)
+func (l *strategicList) set(j int, patch JSONPatch) error {
+	if patch.Operation == "remove" {
+		if j >= len(l.current) {
+			return fmt.Errorf("missing element %d", j)
+		}
+		k, _ := keyOf(l.current[j], l.key)
+		l.removed[k] = true
+		return nil
+	}
+
+	value, err := decode(patch.Value)
+	if err != nil {
+		return err
+	}
+	k, ok := keyOf(value, l.key)
+	if !ok {
+		return fmt.Errorf("missing merge key: %s", l.key)
+	}
+	switch patch.Operation {
+	case "add":
+		l.added[k] = value
+	case "replace":
+		if object, ok := value.(map[string]interface{}); ok {
+			l.patched[k] = replacedObject(object)
+		} else {
+			l.added[k] = value
+		}
+	default:
+		return fmt.Errorf("operation %s is not supported by strategic merge patches", patch.Operation)
+	}
+
+	return nil
+}
+
+// element returns the patch, the current and the modified element at the index j of the current list
+func (l *strategicList) element(j int) (strategicObject, interface{}, interface{}, error) {
+	if j >= len(l.current) || l.key == "" {
+		return nil, nil, nil, fmt.Errorf("missing element %d", j)
+	}
+	k, ok := keyOf(l.current[j], l.key)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("missing merge key: %s", l.key)
+	}
+	idx, ok := l.modifiedIdx[k]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("missing element %d", j)
+	}
+	object, ok := l.patched[k]
+	if !ok {
+		object = strategicObject{l.key: l.current[j].(map[string]interface{})[l.key]}
+		l.patched[k] = object
+	}
+
+	return object, l.current[j], l.modified[idx], nil
+}
+
+// fold adds the list to the patch of its object: the added and patched elements in the order of the modified list,
+// followed by the deleted elements and the order of the modified list
+func (l *strategicList) fold() {
+	if l.object == nil {
+		return
+	}
+
+	var (
+		list    []interface{}
+		deleted []interface{}
+		order   = []interface{}{}
+	)
+	for _, element := range l.modified {
+		k, _ := keyOf(element, l.key)
+		if value, ok := l.added[k]; ok {
+			list = append(list, value)
+		} else if object, ok := l.patched[k]; ok {
+			list = append(list, object)
+		}
+		if l.key == "" {
+			order = append(order, element)
+		} else {
+			order = append(order, map[string]interface{}{l.key: element.(map[string]interface{})[l.key]})
+		}
+	}
+	for _, element := range l.current {
+		if k, _ := keyOf(element, l.key); l.removed[k] {
+			if l.key == "" {
+				deleted = append(deleted, element)
+			} else {
+				list = append(list, map[string]interface{}{l.key: element.(map[string]interface{})[l.key], directivePatch: "delete"})
+			}
+		}
+	}
+
+	if len(list) > 0 {
+		l.object[l.field] = list
+	}
+	if len(deleted) > 0 {
+		l.object[directiveDeleteFromPrimitiveList+l.field] = deleted
+	}
+	l.object[directiveSetElementOrder+l.field] = order
+}
+
+// schemaOf returns the type and the options of the value at the key of an object of the type t, the options of the
+// struct tags take precedence over the options of the walker
+func (w *walker) schemaOf(t reflect.Type, key string, pointer JSONPointer) (reflect.Type, fieldOptions, error) {
+	var (
+		childType reflect.Type
+		options   fieldOptions
+		err       error
+	)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil {
+		switch t.Kind() {
+		case reflect.Struct:
+			if j, ok := structInfoOf(t).indices[key]; ok {
+				childType = t.Field(j).Type
+				if options, err = parseFieldOptions(t.Field(j).Tag, pointer); err != nil {
+					return nil, options, err
+				}
+			}
+		case reflect.Map:
+			childType = t.Elem()
+		}
+	}
+
+	if !options.unordered {
+		if ignore, ok := w.ignoredSliceOf(pointer); ok {
+			options.unordered, options.key = true, ignore.JSONField
+		}
+	}
+	options.atomic = options.atomic || w.isAtomic(pointer)
+
+	return childType, options, nil
+}
+
+// elemTypeOf returns the element type of a slice type or nil
+func elemTypeOf(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		return t.Elem()
+	}
+
+	return nil
+}
+
+// keyOf returns the encoded value of the merge key of a list element or the encoded element if there is no merge key
+func keyOf(element interface{}, key string) (string, bool) {
+	if key != "" {
+		object, ok := element.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if element, ok = object[key]; !ok {
+			return "", false
+		}
+	}
+	raw, _ := json.Marshal(element)
+
+	return string(raw), true
+}
+
+// retainedKeys returns the sorted keys of an object which aren't null
+func retainedKeys(object map[string]interface{}) []interface{} {
+	var keys []string
+	for key, value := range object {
+		if value != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	retained := make([]interface{}, len(keys))
+	for j, key := range keys {
+		retained[j] = key
+	}
+
+	return retained
+}
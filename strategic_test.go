@@ -0,0 +1,197 @@
+package jsonpatch_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+type N struct {
+	Spec   L                 `json:"spec"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Source *O                `json:"source,omitempty" patchStrategy:"retainKeys"`
+	Status string            `json:"status,omitempty" jsonpatch:"readonly"`
+	Args   []string          `json:"args"`
+}
+
+type O struct {
+	Secret    string `json:"secret,omitempty"`
+	ConfigMap string `json:"configMap,omitempty"`
+}
+
+var _ = Describe("CreateStrategicMergePatch", func() {
+	Context("CreateStrategicMergePatch", func() {
+		It("should patch changed and deleted keys", func() {
+			testStrategicMergePatch(
+				N{Labels: map[string]string{"a": "1", "c": "3"}, Args: []string{"x"}, Status: "ready"},
+				N{Labels: map[string]string{"a": "2", "b": "2"}, Args: []string{"y"}},
+				`{"labels":{"a":"1","b":null,"c":"3"},"args":["x"]}`,
+			)
+			testStrategicMergePatch(
+				N{},
+				N{Labels: map[string]string{"a": "1"}},
+				`{"labels":null}`,
+			)
+		})
+		It("should not patch equal objects", func() {
+			testStrategicMergePatch(N{Args: []string{"x"}}, N{Args: []string{"x"}}, `{}`)
+		})
+		It("should merge lists by their merge key", func() {
+			testStrategicMergePatch(
+				N{Spec: L{Containers: []M{{Name: "main", Image: "b"}, {Name: "init", Image: "c"}}}},
+				N{Spec: L{Containers: []M{{Name: "main", Image: "a"}, {Name: "sidecar", Image: "a"}}}},
+				`{"spec":{
+					"containers":[{"name":"main","image":"b"},{"name":"init","image":"c"},{"name":"sidecar","$patch":"delete"}],
+					"$setElementOrder/containers":[{"name":"main"},{"name":"init"}]
+				}}`,
+			)
+		})
+		It("should set the element order of reordered lists", func() {
+			testStrategicMergePatch(
+				N{Spec: L{Containers: []M{{Name: "b"}, {Name: "a"}}}},
+				N{Spec: L{Containers: []M{{Name: "a"}, {Name: "b"}}}},
+				`{"spec":{"$setElementOrder/containers":[{"name":"b"},{"name":"a"}]}}`,
+			)
+		})
+		It("should merge lists of primitives", func() {
+			testStrategicMergePatch(
+				N{Spec: L{Finalizers: []string{"a", "c"}}},
+				N{Spec: L{Finalizers: []string{"a", "b"}}},
+				`{"spec":{
+					"finalizers":["c"],
+					"$deleteFromPrimitiveList/finalizers":["b"],
+					"$setElementOrder/finalizers":["a","c"]
+				}}`,
+			)
+		})
+		It("should replace atomic objects", func() {
+			testStrategicMergePatch(
+				N{Spec: L{Selector: map[string]string{"app": "a"}}},
+				N{Spec: L{Selector: map[string]string{"app": "b", "tier": "c"}}},
+				`{"spec":{"selector":{"$patch":"replace","app":"a"}}}`,
+			)
+		})
+		It("should retain the keys of objects with the retainKeys strategy", func() {
+			testStrategicMergePatch(
+				N{Source: &O{ConfigMap: "b"}},
+				N{Source: &O{Secret: "a"}},
+				`{"source":{"configMap":"b","secret":null,"$retainKeys":["configMap"]}}`,
+			)
+		})
+		It("should merge lists which order is ignored", func() {
+			patch, err := jsonpatch.CreateStrategicMergePatch(
+				D{StructSliceWithKey: []C{{Str: "a", IntMap: map[string]int{"a": 1}}}},
+				D{StructSliceWithKey: []C{{Str: "a"}, {Str: "b"}}},
+				jsonpatch.IgnoreSliceOrderWithPattern([]jsonpatch.IgnorePattern{{Pattern: "/structsWithKey", JSONField: "str"}}),
+			)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(patch).Should(MatchJSON(`{
+				"structsWithKey":[{"str":"a","intmap":{"a":1}},{"str":"b","$patch":"delete"}],
+				"$setElementOrder/structsWithKey":[{"str":"a"}]
+			}`))
+		})
+		It("should fail for invalid values", func() {
+			_, err := jsonpatch.CreateStrategicMergePatch([]string{}, []string{})
+			Ω(err).Should(HaveOccurred())
+		})
+		It("should replace merge lists with duplicated merge keys", func() {
+			testStrategicMergePatch(
+				N{Spec: L{Containers: []M{{Name: "a"}, {Name: "a", Image: "b"}}}},
+				N{Spec: L{Containers: []M{{Name: "b"}}}},
+				`{"spec":{"containers":[{"name":"a","image":""},{"name":"a","image":"b"},{"$patch":"replace"}]}}`,
+			)
+		})
+		It("should apply the predicates", func() {
+			patch, err := jsonpatch.CreateStrategicMergePatch(
+				N{Labels: map[string]string{"a": "1"}, Spec: L{Containers: []M{{Name: "main", Image: "b"}}}},
+				N{Labels: map[string]string{"a": "2"}, Spec: L{Containers: []M{{Name: "main", Image: "a"}}}},
+				jsonpatch.WithPredicate(jsonpatch.ExcludePaths("/labels")),
+			)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(patch).Should(MatchJSON(`{"spec":{
+				"containers":[{"name":"main","image":"b"}],
+				"$setElementOrder/containers":[{"name":"main"}]
+			}}`))
+		})
+		It("should apply the handler", func() {
+			patch, err := jsonpatch.CreateStrategicMergePatch(
+				N{Labels: map[string]string{"a": "1"}},
+				N{Labels: map[string]string{"a": "2"}},
+				jsonpatch.WithHandler(jsonpatch.HandlerFuncs{
+					ReplaceFunc: func(pointer jsonpatch.JSONPointer, _, _ interface{}) []jsonpatch.JSONPatch {
+						return []jsonpatch.JSONPatch{{Operation: "replace", Path: pointer.String(), Value: "handled"}}
+					},
+				}),
+			)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(patch).Should(MatchJSON(`{"labels":{"a":"handled"}}`))
+		})
+		It("should fail for changed immutable values", func() {
+			_, err := jsonpatch.CreateStrategicMergePatch(
+				N{Labels: map[string]string{"a": "1"}},
+				N{Labels: map[string]string{"a": "2"}},
+				jsonpatch.Immutable("/labels"),
+			)
+			Ω(err).Should(BeAssignableToTypeOf(&jsonpatch.ImmutableError{}))
+		})
+		It("should replace the values at the max depth", func() {
+			testStrategicMergePatchWithOptions(
+				N{Labels: map[string]string{"a": "1"}, Spec: L{Containers: []M{{Name: "main", Image: "b"}}}},
+				N{Labels: map[string]string{"a": "2", "b": "2"}, Spec: L{Containers: []M{{Name: "main", Image: "a"}}}},
+				`{"labels":{"$patch":"replace","a":"1"},"spec":{"$patch":"replace","containers":[{"name":"main","image":"b"}],"finalizers":null,"selector":null,"ordered":null}}`,
+				jsonpatch.WithMaxDepth(1),
+			)
+			testStrategicMergePatchWithOptions(
+				N{Spec: L{Containers: []M{{Name: "main", Image: "b"}}}},
+				N{Spec: L{Containers: []M{{Name: "main", Image: "a"}, {Name: "init"}}}},
+				`{"spec":{"containers":[{"name":"main","image":"b"},{"$patch":"replace"}]}}`,
+				jsonpatch.WithMaxDepth(2),
+			)
+		})
+		It("should skip the values which are skipped by the decider", func() {
+			testStrategicMergePatchWithOptions(
+				N{Labels: map[string]string{"a": "1"}, Spec: L{Finalizers: []string{"a"}}},
+				N{Labels: map[string]string{"a": "2"}},
+				`{"labels":{"a":"1"}}`,
+				jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(ctx jsonpatch.DecisionContext) jsonpatch.Decision {
+					if ctx.Pointer.String() == "/spec" {
+						return jsonpatch.SkipSubtree
+					}
+					return jsonpatch.Include
+				})),
+			)
+		})
+		It("should pass the redacted patches to the sink", func() {
+			var redacted []jsonpatch.JSONPatch
+			testStrategicMergePatchWithOptions(
+				P{Name: "a", Password: "secret"},
+				P{Name: "a", Password: "password"},
+				`{"password":"secret"}`,
+				jsonpatch.WithRedactor(jsonpatch.Redactor{}, func(patch jsonpatch.JSONPatch) error {
+					redacted = append(redacted, patch)
+					return nil
+				}),
+			)
+			Ω(redacted).Should(Equal([]jsonpatch.JSONPatch{{Operation: "replace", Path: "/password", Value: "[REDACTED]"}}))
+
+			errSink := errors.New("sink")
+			_, err := jsonpatch.CreateStrategicMergePatch(P{Password: "secret"}, P{}, jsonpatch.WithRedactor(jsonpatch.Redactor{}, func(jsonpatch.JSONPatch) error {
+				return errSink
+			}))
+			Ω(err).Should(MatchError(errSink))
+		})
+	})
+})
+
+func testStrategicMergePatch(modified, current interface{}, expected string) {
+	testStrategicMergePatchWithOptions(modified, current, expected)
+}
+
+func testStrategicMergePatchWithOptions(modified, current interface{}, expected string, options ...jsonpatch.Option) {
+	patch, err := jsonpatch.CreateStrategicMergePatch(modified, current, options...)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(patch).Should(MatchJSON(expected))
+}
@@ -0,0 +1,77 @@
+package jsonpatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+)
+
+// StopWalk can be returned by the function of WalkPatch in order to stop the patch creation early without an error
+var StopWalk = errors.New("stop walk")
+
+// WalkPatch compares two JSON data structures like CreateJSONPatch, but calls the function for every JSONPatch as soon
+// as it is created instead of collecting all of them. If the function returns an error the patch creation is stopped and
+// the error is returned, unless it is StopWalk. Patches which might still be collapsed or dropped (see
+// WithCollapseThreshold and WithDecider) are emitted as soon as this is decided. The function might already be called
+// for some patches if the patch creation fails.
+func WalkPatch(fn func(JSONPatch) error, modified, current interface{}, options ...Option) error {
+	return WalkPatchContext(context.Background(), fn, modified, current, options...)
+}
+
+// WalkPatchContext calls the function for every JSONPatch like WalkPatch, but aborts the patch creation with the error of
+// the context as soon as it is canceled or its deadline is exceeded (see CreateJSONPatchContext)
+func WalkPatchContext(ctx context.Context, fn func(JSONPatch) error, modified, current interface{}, options ...Option) error {
+	w := newWalkerContext(ctx, options...)
+	w.modifiedRoot, w.currentRoot = modified, current
+	w.emit = fn
+
+	if err := w.walk(reflect.ValueOf(modified), reflect.ValueOf(current), reflect.Value{}, w.prefix); err != nil {
+		return err
+	}
+	w.flush()
+	if w.stopped != nil && !errors.Is(w.stopped, StopWalk) {
+		return w.stopped
+	}
+
+	return w.err()
+}
+
+// WritePatch compares two JSON data structures like CreateJSONPatch and writes the encoded JSONPatch to the writer
+// while it is created (see WalkPatch)
+func WritePatch(writer io.Writer, modified, current interface{}, options ...Option) error {
+	return WritePatchContext(context.Background(), writer, modified, current, options...)
+}
+
+// WritePatchContext writes the encoded JSONPatch like WritePatch, but aborts the patch creation with the error of the
+// context as soon as it is canceled or its deadline is exceeded (see CreateJSONPatchContext). The patch might already be
+// partially written if the patch creation fails.
+func WritePatchContext(ctx context.Context, writer io.Writer, modified, current interface{}, options ...Option) error {
+	separator := "["
+	if err := WalkPatchContext(ctx, func(patch JSONPatch) error {
+		raw, err := json.Marshal(patch)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(writer, separator); err != nil {
+			return err
+		}
+		if _, err := writer.Write(raw); err != nil {
+			return err
+		}
+		separator = ","
+		return nil
+	}, modified, current, options...); err != nil {
+		return err
+	}
+
+	if separator == "[" {
+		// no patches were written
+		_, err := io.WriteString(writer, "[]")
+		return err
+	}
+	_, err := io.WriteString(writer, "]")
+
+	return err
+}
@@ -0,0 +1,155 @@
+package jsonpatch_test
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/go-faker/faker/v4/pkg/options"
+
+	"github.com/snorwin/jsonpatch"
+)
+
+var _ = Describe("Streaming", func() {
+	var (
+		modified G
+		current  G
+	)
+	BeforeEach(func() {
+		modified = G{C: C{Str: "new", StrMap: map[string]string{"a": "1", "b": "2"}}, D: D{StringSlice: []string{"x", "y"}}}
+		current = G{C: C{Str: "old", StrMap: map[string]string{"a": "2"}}}
+	})
+
+	testWalkPatch := func(modified, current interface{}, options ...jsonpatch.Option) {
+		list, err := jsonpatch.CreateJSONPatch(modified, current, options...)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		var patches []jsonpatch.JSONPatch
+		Ω(jsonpatch.WalkPatch(func(patch jsonpatch.JSONPatch) error {
+			patches = append(patches, patch)
+			return nil
+		}, modified, current, options...)).Should(Succeed())
+		// the order of the patches of map entries is random
+		Ω(patches).Should(ConsistOf(list.List()))
+	}
+
+	Context("WalkPatch", func() {
+		It("should emit the same patches as CreateJSONPatch", func() {
+			testWalkPatch(modified, current)
+			testWalkPatch(modified, current, jsonpatch.WithCollapseThreshold(1))
+			testWalkPatch(modified, current, jsonpatch.WithDecider(jsonpatch.DeciderFunc(func(context jsonpatch.DecisionContext) jsonpatch.Decision {
+				if context.Pointer.String() == "/d/strs" {
+					return jsonpatch.Skip
+				}
+				return jsonpatch.Include
+			})))
+		})
+		It("should emit the same patches as CreateJSONPatch for random data", func() {
+			for i := 0; i < 10; i++ {
+				modified, current := G{}, G{}
+				Ω(faker.FakeData(&modified, options.WithRandomMapAndSliceMaxSize(5))).Should(Succeed())
+				Ω(faker.FakeData(&current, options.WithRandomMapAndSliceMaxSize(5))).Should(Succeed())
+				testWalkPatch(modified, current)
+				testWalkPatch(modified, current, jsonpatch.WithCollapseThreshold(2))
+			}
+		})
+		It("should emit the patches while they are created", func() {
+			var events []string
+			Ω(jsonpatch.WalkPatch(func(patch jsonpatch.JSONPatch) error {
+				events = append(events, "emit "+patch.Path)
+				return nil
+			}, modified, current, jsonpatch.WithPredicate(jsonpatch.Funcs{
+				ReplaceFunc: func(pointer jsonpatch.JSONPointer, _, _ interface{}) bool {
+					events = append(events, "walk "+pointer.String())
+					return true
+				},
+			}))).Should(Succeed())
+			Ω(events).Should(ContainElements("emit /c/str", "walk /d"))
+			Ω(indexOf(events, "emit /c/str")).Should(BeNumerically("<", indexOf(events, "walk /d")))
+		})
+		It("should stop early", func() {
+			var patches []jsonpatch.JSONPatch
+			Ω(jsonpatch.WalkPatch(func(patch jsonpatch.JSONPatch) error {
+				patches = append(patches, patch)
+				if len(patches) == 2 {
+					return jsonpatch.StopWalk
+				}
+				return nil
+			}, modified, current)).Should(Succeed())
+			Ω(patches).Should(HaveLen(2))
+
+			errStop := errors.New("stop")
+			Ω(jsonpatch.WalkPatch(func(jsonpatch.JSONPatch) error {
+				return errStop
+			}, modified, current)).Should(MatchError(errStop))
+		})
+		It("should stop early if the values can't be collapsed", func() {
+			modified, current := map[string]string{}, map[string]string{}
+			for j := 0; j < 1000; j++ {
+				modified[strconv.Itoa(j)] = "new"
+				current[strconv.Itoa(j)] = "old"
+			}
+			calls := 0
+			Ω(jsonpatch.WalkPatch(func(jsonpatch.JSONPatch) error {
+				return jsonpatch.StopWalk
+			}, modified, current, jsonpatch.WithCollapseThreshold(1), jsonpatch.WithPredicate(jsonpatch.Funcs{
+				ReplaceFunc: func(jsonpatch.JSONPointer, interface{}, interface{}) bool {
+					calls++
+					return true
+				},
+			}))).Should(Succeed())
+			// only the first entry is patched
+			Ω(calls).Should(Equal(1))
+		})
+		It("should fail", func() {
+			Ω(jsonpatch.WalkPatch(func(jsonpatch.JSONPatch) error {
+				return nil
+			}, A{}, B{})).ShouldNot(Succeed())
+		})
+		It("should redact the emitted patches", func() {
+			var emitted, redacted []jsonpatch.JSONPatch
+			Ω(jsonpatch.WalkPatch(func(patch jsonpatch.JSONPatch) error {
+				emitted = append(emitted, patch)
+				// the redacted copy is passed to the sink before the patch is emitted
+				Ω(redacted).Should(HaveLen(len(emitted)))
+				return nil
+			}, modified, current, jsonpatch.WithRedactor(jsonpatch.Redactor{Patterns: []string{"/c/strmap/*"}}, func(patch jsonpatch.JSONPatch) error {
+				redacted = append(redacted, patch)
+				return nil
+			}))).Should(Succeed())
+			Ω(redacted).Should(ContainElement(jsonpatch.JSONPatch{Operation: "add", Path: "/c/strmap/b", Value: "[REDACTED]"}))
+		})
+	})
+	Context("WritePatch", func() {
+		It("should write the encoded patch", func() {
+			// a single patch for the map entries, their order is random
+			current.C.StrMap["b"] = "2"
+			list, err := jsonpatch.CreateJSONPatch(modified, current)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			var buffer bytes.Buffer
+			Ω(jsonpatch.WritePatch(&buffer, modified, current)).Should(Succeed())
+			Ω(buffer.Bytes()).Should(MatchJSON(list.Raw()))
+		})
+		It("should write an empty patch", func() {
+			var buffer bytes.Buffer
+			Ω(jsonpatch.WritePatch(&buffer, modified, modified)).Should(Succeed())
+			Ω(buffer.String()).Should(Equal("[]"))
+		})
+	})
+})
+
+// indexOf returns the index of the first occurrence of the element or -1
+func indexOf(elements []string, element string) int {
+	for j, e := range elements {
+		if e == element {
+			return j
+		}
+	}
+
+	return -1
+}
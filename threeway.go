@@ -0,0 +1,249 @@
+package jsonpatch
+
+import (
+	"reflect"
+	"strconv"
+)
+
+const (
+	// maxAlignmentSize limits the number of element comparisons used to align the elements of two ordered slices,
+	// elements of larger slices are only aligned by their common prefix and suffix
+	maxAlignmentSize = 1 << 16
+)
+
+// processSliceThreeWay processes the elements of ordered slices in a three-way patch. The elements of the modified and
+// the current slice are aligned with the elements of the original slice, in order to identify which elements were
+// added, removed or changed on either side:
+//   - elements which are unchanged in the modified slice are kept as they are in the current slice
+//   - elements which were changed in the modified slice are patched, their index is computed against the current slice
+//   - elements which were removed from the modified slice are removed from the current slice
+//   - elements which were added to the modified slice are added after the preceding element of the modified slice
+//
+// This way elements which were added to (or removed from) the current slice by someone else are kept, and they don't
+// shift the indices of the patch.
+func (w *walker) processSliceThreeWay(modified, current, original reflect.Value, pointer JSONPointer) error {
+	if !original.IsValid() {
+		original = reflect.MakeSlice(modified.Type(), 0, 0)
+	}
+
+	// the elements are compared by their canonical keys, which are computed once per element
+	originalKeys, modifiedKeys, currentKeys := w.keysOf(original), w.keysOf(modified), w.keysOf(current)
+
+	// match the elements of the original slice with the modified and current slice
+	originalToModified, unchangedModified, err := w.match(originalKeys, modifiedKeys)
+	if err != nil {
+		return err
+	}
+	originalToCurrent, _, err := w.match(originalKeys, currentKeys)
+	if err != nil {
+		return err
+	}
+	modifiedToOriginal, currentToOriginal := invert(originalToModified), invert(originalToCurrent)
+
+	// match the elements which were added on both sides with each other
+	var addedModified, addedCurrent []int
+	for k := 0; k < modified.Len(); k++ {
+		if _, ok := modifiedToOriginal[k]; !ok {
+			addedModified = append(addedModified, k)
+		}
+	}
+	for k := 0; k < current.Len(); k++ {
+		if _, ok := currentToOriginal[k]; !ok {
+			addedCurrent = append(addedCurrent, k)
+		}
+	}
+	aligned, err := w.align(elementsOf(modifiedKeys, addedModified), elementsOf(currentKeys, addedCurrent))
+	if err != nil {
+		return err
+	}
+	modifiedToCurrent := map[int]int{}
+	for k, l := range aligned {
+		modifiedToCurrent[addedModified[k]] = addedCurrent[l]
+	}
+	for k, i := range modifiedToOriginal {
+		if l, ok := originalToCurrent[i]; ok {
+			modifiedToCurrent[k] = l
+		}
+	}
+
+	// the elements of the modified slice which are not part of the current slice are added after the current element
+	// which corresponds to the preceding element of the modified slice (-1 is used for the beginning of the slice)
+	added := map[int][]int{}
+	after := -1
+	for k := 0; k < modified.Len(); k++ {
+		if l, ok := modifiedToCurrent[k]; ok {
+			after = l
+		} else if i, ok := modifiedToOriginal[k]; !ok || !unchangedModified[i] {
+			added[after] = append(added[after], k)
+		}
+	}
+
+	// index is the index of the next element in the patched slice
+	var index int
+	add := func(after int) error {
+		for _, k := range added[after] {
+			if err := w.ctx.Err(); err != nil {
+				return err
+			}
+			if ok, err := w.resolveKeys(pointer.Add(strconv.Itoa(index)), modified.Index(k), reflect.Value{}, indexOf(original, modifiedToOriginal, k), &modifiedKeys[k], nil, keyAt(originalKeys, modifiedToOriginal, k)); err != nil {
+				return err
+			} else if ok && w.add(pointer.Add(strconv.Itoa(index)), modified.Index(k).Interface()) {
+				index++
+			}
+		}
+
+		return nil
+	}
+
+	if err := add(-1); err != nil {
+		return err
+	}
+	for l := 0; l < current.Len(); l++ {
+		i, inOriginal := currentToOriginal[l]
+		k, inModified := originalToModified[i]
+		if inOriginal && !inModified {
+			// the element was removed from the modified slice
+			if err := w.ctx.Err(); err != nil {
+				return err
+			}
+			if ok, err := w.resolveKeys(pointer.Add(strconv.Itoa(index)), reflect.Value{}, current.Index(l), original.Index(i), nil, &currentKeys[l], &originalKeys[i]); err != nil {
+				return err
+			} else if !ok || !w.remove(pointer.Add(strconv.Itoa(index)), current.Index(l).Interface()) {
+				index++
+			}
+		} else {
+			if inOriginal && !unchangedModified[i] {
+				// the element was changed in the modified slice
+				if err := w.walk(modified.Index(k), current.Index(l), original.Index(i), pointer.Add(strconv.Itoa(index))); err != nil {
+					return err
+				}
+			}
+			index++
+		}
+
+		if err := add(l); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// match matches the elements of the slice b with the elements of the slice a by their keys. Equal elements are aligned
+// first and the remaining elements in between are matched by their position as changed elements. It returns a map from
+// the indices of a to the indices of b and the set of indices of a which have an equal element in b.
+func (w *walker) match(a, b []valueKey) (map[int]int, map[int]bool, error) {
+	aligned, err := w.align(a, b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matched, unchanged := map[int]int{}, map[int]bool{}
+	var i, j int
+	for i <= len(a) {
+		// look for the next aligned element
+		ni, nj := i, len(b)
+		for ; ni < len(a); ni++ {
+			if k, ok := aligned[ni]; ok {
+				nj = k
+				break
+			}
+		}
+		for k := 0; i+k < ni && j+k < nj; k++ {
+			matched[i+k] = j + k
+		}
+		if ni < len(a) {
+			matched[ni], unchanged[ni] = nj, true
+		}
+		i, j = ni+1, nj+1
+	}
+
+	return matched, unchanged, nil
+}
+
+// align aligns the elements of the slice b with the equal elements of the slice a by their keys using their longest
+// common subsequence and returns a map from the indices of a to the indices of b. The alignment is aborted with the
+// error of the context as soon as it is canceled.
+func (w *walker) align(a, b []valueKey) (map[int]int, error) {
+	aligned := map[int]int{}
+
+	// the common prefix and suffix are aligned directly
+	var prefix int
+	for prefix < len(a) && prefix < len(b) && !a[prefix].differs(b[prefix]) {
+		aligned[prefix] = prefix
+		prefix++
+	}
+	var suffix int
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && !a[len(a)-1-suffix].differs(b[len(b)-1-suffix]) {
+		aligned[len(a)-1-suffix] = len(b) - 1 - suffix
+		suffix++
+	}
+
+	n, m := len(a)-prefix-suffix, len(b)-prefix-suffix
+	if n == 0 || m == 0 || n*m > maxAlignmentSize {
+		return aligned, w.ctx.Err()
+	}
+
+	// lengths[i][j] is the length of the longest common subsequence of a[prefix+i:] and b[prefix+j:]
+	equal := make([][]bool, n)
+	lengths := make([][]int, n+1)
+	for i := range lengths {
+		lengths[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		if err := w.ctx.Err(); err != nil {
+			return nil, err
+		}
+		equal[i] = make([]bool, m)
+		for j := m - 1; j >= 0; j-- {
+			if equal[i][j] = !a[prefix+i].differs(b[prefix+j]); equal[i][j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case equal[i][j]:
+			aligned[prefix+i] = prefix + j
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return aligned, nil
+}
+
+// elementsOf returns a new slice with the elements at the indices of the slice
+func elementsOf[E any](s []E, indices []int) []E {
+	elements := make([]E, 0, len(indices))
+	for _, j := range indices {
+		elements = append(elements, s[j])
+	}
+
+	return elements
+}
+
+// keyAt returns the key of the element which is mapped to the index or nil if there is none
+func keyAt(keys []valueKey, indices map[int]int, k int) *valueKey {
+	if j, ok := indices[k]; ok {
+		return &keys[j]
+	}
+
+	return nil
+}
+
+// invert inverts a map of indices
+func invert(m map[int]int) map[int]int {
+	inverted := make(map[int]int, len(m))
+	for k, v := range m {
+		inverted[v] = k
+	}
+
+	return inverted
+}
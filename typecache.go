@@ -0,0 +1,63 @@
+package jsonpatch
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// structInfos caches the structInfo of struct types
+var structInfos sync.Map // map[reflect.Type]*structInfo
+
+// structInfo is the cached reflection metadata of a struct type
+type structInfo struct {
+	// fields are the exported struct fields with a JSON name which are walked
+	fields []fieldInfo
+
+	// indices maps the JSON names of all struct fields to the index of the first field with the name
+	indices map[string]int
+}
+
+// fieldInfo is the cached reflection metadata of a struct field
+type fieldInfo struct {
+	index   int
+	name    string
+	tag     reflect.StructTag
+	options fieldOptions
+	invalid bool
+}
+
+// structInfoOf returns the cached structInfo of a struct type
+func structInfoOf(t reflect.Type) *structInfo {
+	if info, ok := structInfos.Load(t); ok {
+		return info.(*structInfo)
+	}
+
+	info := &structInfo{indices: map[string]int{}}
+	for j := 0; j < t.NumField(); j++ {
+		field := t.Field(j)
+		name := strings.Split(field.Tag.Get(jsonTag), ",")[0]
+		if _, ok := info.indices[name]; !ok {
+			info.indices[name] = j
+		}
+		if name == "" || name == "_" || !field.IsExported() {
+			// struct fields without a JSON tag set or unexported fields are ignored
+			continue
+		}
+		options, err := parseFieldOptions(field.Tag, nil)
+		info.fields = append(info.fields, fieldInfo{index: j, name: name, tag: field.Tag, options: options, invalid: err != nil})
+	}
+
+	actual, _ := structInfos.LoadOrStore(t, info)
+
+	return actual.(*structInfo)
+}
+
+// optionsAt returns the options of the field, the error of invalid options refers to the pointer
+func (f fieldInfo) optionsAt(pointer JSONPointer) (fieldOptions, error) {
+	if f.invalid {
+		return parseFieldOptions(f.tag, pointer)
+	}
+
+	return f.options, nil
+}
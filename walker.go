@@ -1,16 +1,25 @@
 package jsonpatch
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
-	jsonTag = "json"
+	jsonTag          = "json"
+	patchTag         = "jsonpatch"
+	patchStrategyTag = "patchStrategy"
+	patchMergeKeyTag = "patchMergeKey"
 )
 
 type walker struct {
@@ -19,73 +28,444 @@ type walker struct {
 	prefix        []string
 	patchList     []JSONPatch
 	ignoredSlices []IgnorePattern
+	threeWay      bool
+	resolver      ConflictResolver
+	conflicts     []Conflict
+	collapseRatio float64
+	maxDepth      int
+	atomic        []string
+	decider       Decider
+	parents       []parent
+	modifiedRoot  interface{}
+	currentRoot   interface{}
+	collectErrors bool
+	errs          []error
+	immutable     []string
+	violations    []JSONPointer
+	redactor      *Redactor
+	redactedSink  func(JSONPatch) error
+	hash          HashFunc
+	emit          func(JSONPatch) error
+	holds         int
+	stopped       error
+	measurements  []measurement
+	unordered     func(pointer JSONPointer, modified, current reflect.Value)
+	concurrency   int
+	options       []Option
+	parent        *walker
+	index         int
+	halted        *atomic.Int64
+	mu            sync.Mutex
+	ctx           context.Context
+}
+
+// newWalker creates a new walker and applies the options to it
+func newWalker(options ...Option) *walker {
+	return newWalkerContext(context.Background(), options...)
+}
+
+// newWalkerContext creates a new walker which walk is canceled with the context and applies the options to it
+func newWalkerContext(ctx context.Context, options ...Option) *walker {
+	w := &walker{
+		handler:  &DefaultHandler{},
+		prefix:   []string{""},
+		resolver: ResolveOurs,
+		options:  options,
+		ctx:      ctx,
+	}
+
+	for _, apply := range options {
+		apply(w)
+	}
+	if w.predicate == nil {
+		w.predicate = Funcs{}
+	}
+
+	return w
+}
+
+// addPredicate sets the Predicate of the walker or combines it with the existing one
+func (w *walker) addPredicate(predicate Predicate) {
+	if w.predicate == nil {
+		w.predicate = predicate
+	} else {
+		w.predicate = And(w.predicate, predicate)
+	}
+}
+
+// fail records an error of a Predicate, Handler or Decider at the pointer. Unless all errors are collected, the walk is
+// aborted after the first error.
+func (w *walker) fail(pointer JSONPointer, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.errs = append(w.errs, fmt.Errorf("%w at: %s", err, pointer))
+	// the other forks are stopped as well
+	if w.halted != nil && !w.collectErrors {
+		w.halt()
+	}
+}
+
+// aborted returns true if the walk is aborted due to an error or because the emission of the patches was stopped
+func (w *walker) aborted() bool {
+	if w.halted != nil && w.halted.Load() < int64(w.index) || w.parent != nil && w.parent.aborted() {
+		return true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stopped != nil || !w.collectErrors && len(w.errs) > 0
+}
+
+// flush emits the created patches if they are streamed, unless they might still be rewritten (e.g. collapsed)
+func (w *walker) flush() {
+	if w.emit == nil || w.holds > 0 || w.stopped != nil {
+		return
+	}
+	if err := w.redact(w.patchList); err != nil {
+		w.stopped = err
+		return
+	}
+	for _, patch := range w.patchList {
+		if err := w.emit(patch); err != nil {
+			w.stopped = err
+			break
+		}
+	}
+	w.patchList = w.patchList[:0]
+	w.measurements = w.measurements[:0]
+}
+
+// err returns the recorded errors or nil, all changes of immutable values are reported in a single ImmutableError
+func (w *walker) err() error {
+	errs := w.errs
+	if len(w.violations) > 0 {
+		errs = append(errs, &ImmutableError{Pointers: w.violations})
+	}
+
+	if len(errs) == 0 {
+		return nil
+	} else if !w.collectErrors {
+		return errs[0]
+	}
+
+	return errors.Join(errs...)
 }
 
 // walk recursively processes the modified and current JSON data structures simultaneously and in every step it compares
-// the value of them with each other
-func (w *walker) walk(modified, current reflect.Value, pointer JSONPointer) error {
+// the value of them with each other. For three-way patches the original JSON data structure is processed alongside, an
+// invalid original value means that the value was not part of the original JSON.
+func (w *walker) walk(modified, current, original reflect.Value, pointer JSONPointer) error {
+	if w.aborted() {
+		return nil
+	}
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
 	// the data structures of both JSON objects must be identical
 	if modified.Kind() != current.Kind() {
 		return fmt.Errorf("kind does not match at: %s modified: %s current: %s", pointer, modified.Kind(), current.Kind())
 	}
+	if original.IsValid() && (!modified.IsValid() || original.Type() != modified.Type()) {
+		// an original value of a different type can't be compared and is treated as if it did not exist
+		original = reflect.Value{}
+	}
+	if w.identical(modified, current) {
+		// unchanged values never create patches
+		return nil
+	}
+	if w.decider != nil {
+		switch modified.Kind() {
+		case reflect.Struct, reflect.Slice, reflect.Map:
+			return w.processDecision(modified, current, original, pointer)
+		}
+	}
+
+	return w.process(modified, current, original, pointer)
+}
+
+// process processes the values according to their kind
+func (w *walker) process(modified, current, original reflect.Value, pointer JSONPointer) error {
+	if w.isAtomic(pointer) {
+		switch modified.Kind() {
+		case reflect.Struct, reflect.Pointer, reflect.Slice, reflect.Map:
+			return w.processAtomic(modified, current, original, pointer)
+		}
+	}
 	switch modified.Kind() {
 	case reflect.Struct:
-		return w.processStruct(modified, current, pointer)
+		return w.processCollapsible(modified, current, pointer, func() error {
+			return w.processStruct(modified, current, original, pointer)
+		})
 	case reflect.Pointer:
-		return w.processPtr(modified, current, pointer)
+		return w.processPtr(modified, current, original, pointer)
 	case reflect.Slice:
-		return w.processSlice(modified, current, pointer)
+		return w.processCollapsible(modified, current, pointer, func() error {
+			return w.processSlice(modified, current, original, pointer)
+		})
 	case reflect.Map:
-		return w.processMap(modified, current, pointer)
+		return w.processCollapsible(modified, current, pointer, func() error {
+			return w.processMap(modified, current, original, pointer)
+		})
 	case reflect.Interface:
-		return w.processInterface(modified, current, pointer)
+		return w.processInterface(modified, current, original, pointer)
 	case reflect.String:
-		return w.processString(modified, current, pointer)
+		return w.processString(modified, current, original, pointer)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		if modified.Int() != current.Int() {
-			w.replace(pointer, modified.Int(), current.Int())
-		}
+		return w.processScalar(modified, current, original, pointer, modified.Int(), current.Int())
 	case reflect.Uint, reflect.Uintptr, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		if modified.Uint() != current.Uint() {
-			w.replace(pointer, modified.Uint(), current.Uint())
-		}
+		return w.processScalar(modified, current, original, pointer, modified.Uint(), current.Uint())
 	case reflect.Float32:
-		if modified.Float() != current.Float() {
-			w.replace(pointer, float32(modified.Float()), float32(current.Float()))
-		}
+		return w.processScalar(modified, current, original, pointer, float32(modified.Float()), float32(current.Float()))
 	case reflect.Float64:
-		if modified.Float() != current.Float() {
-			w.replace(pointer, modified.Float(), current.Float())
-		}
+		return w.processScalar(modified, current, original, pointer, modified.Float(), current.Float())
 	case reflect.Bool:
-		if modified.Bool() != current.Bool() {
-			w.replace(pointer, modified.Bool(), current.Bool())
-		}
+		return w.processScalar(modified, current, original, pointer, modified.Bool(), current.Bool())
 	case reflect.Invalid:
 		// undefined interfaces are ignored for now
 		return nil
 	default:
 		return fmt.Errorf("unsupported kind: %s at: %s", modified.Kind(), pointer)
 	}
+}
+
+// processCollapsible processes a struct, slice or map value and replaces the patches which were created for its children by a
+// single replace of the value, if their encoded size exceeds the size of the replace by the collapse ratio
+func (w *walker) processCollapsible(modified, current reflect.Value, pointer JSONPointer, process func() error) error {
+	if w.collapseRatio <= 0 || w.threeWay {
+		return process()
+	}
+	start, depth := len(w.patchList), len(w.measurements)
+	if !w.collapsible(modified, current, pointer) {
+		// the patches of values which can't be collapsed are emitted right away
+		err := process()
+		w.measurements = w.measurements[:min(depth, len(w.measurements))]
+		return err
+	}
+
+	// the patches are not emitted before it's decided whether they are collapsed
+	w.holds++
+	defer func() {
+		w.holds--
+		w.flush()
+	}()
+	if err := process(); err != nil {
+		return err
+	}
+
+	size, err := w.measure(start, depth)
+	if err != nil || len(w.patchList)-start < 2 {
+		w.measurements = append(w.measurements, measurement{start: start, end: len(w.patchList), size: size})
+		return err
+	}
+	// the size of the replace is estimated by its default encoding, the replace is only created if it is taken
+	replaceSize, err := encodedSize([]JSONPatch{{Operation: "replace", Path: pointer.String(), Value: modified.Interface()}})
+	if err != nil {
+		return err
+	}
+	if float64(size) > w.collapseRatio*float64(replaceSize) {
+		children := append([]JSONPatch{}, w.patchList[start:]...)
+		w.patchList = w.patchList[:start]
+		if w.replace(pointer, modified.Interface(), current.Interface()) {
+			if size, err = w.measure(start, depth); err != nil {
+				return err
+			}
+		} else {
+			w.patchList = append(w.patchList, children...)
+		}
+	}
+	w.measurements = append(w.measurements, measurement{start: start, end: len(w.patchList), size: size})
+
+	return nil
+}
+
+// collapsible reports whether the patches of a value can be collapsed: the value must exist in the current JSON, the
+// root value is never replaced and values which contain immutable values are never replaced as a whole
+func (w *walker) collapsible(modified, current reflect.Value, pointer JSONPointer) bool {
+	if len(pointer) < 2 || modified.Kind() != reflect.Struct && (modified.IsNil() || current.IsNil()) {
+		return false
+	}
+
+	return !matchPaths(pointer, w.immutable) && !parentOfPaths(pointer, w.immutable)
+}
+
+// measurement is the encoded size of the patches of a collapsible value in the patch list
+type measurement struct {
+	start int
+	end   int
+	size  int
+}
+
+// measure returns the encoded size of the patches from the start of the patch list. The sizes of the patches of
+// collapsible children are taken from their measurements (from the depth on), which are dropped afterward, hence every
+// patch is only encoded once.
+func (w *walker) measure(start, depth int) (int, error) {
+	var size int
+	for _, m := range w.measurements[depth:] {
+		leaves, err := encodedSize(w.patchList[start:m.start])
+		if err != nil {
+			return 0, err
+		}
+		size, start = size+leaves+m.size, m.end
+	}
+	w.measurements = w.measurements[:depth]
+
+	leaves, err := encodedSize(w.patchList[start:])
+
+	return size + leaves, err
+}
+
+// encodedSize returns the size of the encoded patches, separators between them included
+func encodedSize(patches []JSONPatch) (int, error) {
+	var size int
+	for _, patch := range patches {
+		raw, err := json.Marshal(patch)
+		if err != nil {
+			return 0, err
+		}
+		size += len(raw) + 1
+	}
+
+	return size, nil
+}
+
+// processDecision processes struct, slice and map values according to the Decision of the Decider
+func (w *walker) processDecision(modified, current, original reflect.Value, pointer JSONPointer) error {
+	decision := w.decider.Decide(w.decisionContext(pointer, "", modified.Interface(), current.Interface()))
+	switch decision {
+	case SkipSubtree:
+		return nil
+	case ReplaceWhole:
+		return w.processAtomic(modified, current, original, pointer)
+	case Fail:
+		w.fail(pointer, ErrDecisionFailed)
+		return nil
+	}
+
+	if decision == Skip {
+		// the patches are not emitted before the patches of the value itself are dropped
+		w.holds++
+		defer func() {
+			w.holds--
+			w.flush()
+		}()
+	}
+
+	start, depth := len(w.patchList), len(w.measurements)
+	w.parents = append(w.parents, parent{length: len(pointer), modified: modified.Interface(), current: current.Interface()})
+	err := w.process(modified, current, original, pointer)
+	w.parents = w.parents[:len(w.parents)-1]
+
+	if decision == Skip {
+		// drop the patches of the value itself, but keep the patches of its children
+		patchList := w.patchList[:start]
+		for _, patch := range w.patchList[start:] {
+			if patch.Path != pointer.String() {
+				patchList = append(patchList, patch)
+			}
+		}
+		w.patchList = patchList
+		// the measured patches might have been dropped
+		w.measurements = w.measurements[:depth]
+	}
+
+	return err
+}
+
+// decide returns true if the Decider includes the patch
+func (w *walker) decide(pointer JSONPointer, operation string, modified, current interface{}) bool {
+	if w.decider == nil {
+		return true
+	}
+
+	switch w.decider.Decide(w.decisionContext(pointer, operation, modified, current)) {
+	case Skip, SkipSubtree:
+		return false
+	case Fail:
+		w.fail(pointer, ErrDecisionFailed)
+		return false
+	}
+
+	return true
+}
+
+// isAtomic returns true if the value at the pointer is patched as a whole, either because it is beyond the max depth
+// or because the pointer matches an atomic pattern
+func (w *walker) isAtomic(pointer JSONPointer) bool {
+	if w.maxDepth > 0 && len(pointer)-len(w.prefix) >= w.maxDepth {
+		return true
+	}
+	for _, pattern := range w.atomic {
+		// a trailing wildcard matches the children of a value, but not the value itself
+		if len(pointer) >= len(ParseJSONPointer(pattern)) && pointer.Match(pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// processAtomic processes struct, pointer, slice and map values as a whole without recursing into them
+func (w *walker) processAtomic(modified, current, original reflect.Value, pointer JSONPointer) error {
+	if !w.changed(modified, current) {
+		return nil
+	}
+	if modified.Kind() == reflect.Pointer {
+		original = elemOf(original)
+	}
+	if ok, err := w.resolve(pointer, modified, current, original); !ok || err != nil {
+		return err
+	}
+
+	switch {
+	case modified.Kind() == reflect.Pointer && modified.IsNil():
+		if w.removable(nonZero(original)) {
+			w.remove(pointer, current.Elem().Interface())
+		}
+	case modified.Kind() == reflect.Pointer && current.IsNil():
+		w.add(pointer, modified.Elem().Interface())
+	case modified.Kind() == reflect.Pointer:
+		w.replace(pointer, modified.Elem().Interface(), current.Elem().Interface())
+	case modified.Kind() != reflect.Struct && current.Len() == 0:
+		w.add(pointer, modified.Interface())
+	default:
+		w.replace(pointer, modified.Interface(), current.Interface())
+	}
 
 	return nil
 }
 
 // processInterface processes reflect.Interface values
-func (w *walker) processInterface(modified reflect.Value, current reflect.Value, pointer JSONPointer) error {
+func (w *walker) processInterface(modified, current, original reflect.Value, pointer JSONPointer) error {
 	// extract the value form the interface and try to process it further
-	if err := w.walk(reflect.ValueOf(modified.Interface()), reflect.ValueOf(current.Interface()), pointer); err != nil {
+	if err := w.walk(reflect.ValueOf(modified.Interface()), reflect.ValueOf(current.Interface()), elemOf(original), pointer); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// processScalar processes values of built-in types (e.g. reflect.Int, reflect.Bool) which are compared by the given values
+func (w *walker) processScalar(modified, current, original reflect.Value, pointer JSONPointer, m, c interface{}) error {
+	if m != c {
+		if ok, err := w.resolve(pointer, modified, current, orZero(original, modified.Type())); !ok || err != nil {
+			return err
+		}
+		w.replace(pointer, m, c)
+	}
+
+	return nil
+}
+
 // processString processes reflect.String values
-func (w *walker) processString(modified reflect.Value, current reflect.Value, pointer JSONPointer) error {
+func (w *walker) processString(modified, current, original reflect.Value, pointer JSONPointer) error {
 	if modified.String() != current.String() {
+		if ok, err := w.resolve(pointer, modified, current, orZero(original, modified.Type())); !ok || err != nil {
+			return err
+		}
 		if modified.String() == "" {
-			w.remove(pointer, current.String())
+			if w.removable(nonZero(original)) {
+				w.remove(pointer, current.String())
+			}
 		} else if current.String() == "" {
 			w.add(pointer, modified.String())
 		} else {
@@ -97,39 +477,99 @@ func (w *walker) processString(modified reflect.Value, current reflect.Value, po
 }
 
 // processMap processes reflect.Map values
-func (w *walker) processMap(modified reflect.Value, current reflect.Value, pointer JSONPointer) error {
+func (w *walker) processMap(modified, current, original reflect.Value, pointer JSONPointer) error {
 	// NOTE: currently only map[string]interface{} are supported
 	if len(modified.MapKeys()) > 0 && len(current.MapKeys()) == 0 {
+		if ok, err := w.resolve(pointer, modified, current, original); !ok || err != nil {
+			return err
+		}
 		w.add(pointer, modified.Interface())
 	} else {
-		it := modified.MapRange()
-		for it.Next() {
-			key := it.Key()
-			if key.Kind() != reflect.String {
-				return fmt.Errorf("only strings are supported as map keys but was: %s at: %s", key.Kind(), pointer)
-			}
-
-			val1 := it.Value()
+		walkEntry := func(w *walker, key, val1 reflect.Value) error {
 			val2 := current.MapIndex(key)
 			if val2.Kind() == reflect.Invalid {
-				w.add(pointer.Add(key.String()), val1.Interface())
-			} else {
-				if err := w.walk(val1, val2, pointer.Add(key.String())); err != nil {
+				if err := w.ctx.Err(); err != nil {
 					return err
 				}
+				if ok, err := w.resolve(pointer.Add(key.String()), val1, val2, mapIndexOf(original, key)); err != nil {
+					return err
+				} else if ok {
+					w.add(pointer.Add(key.String()), val1.Interface())
+				}
+				return nil
 			}
+
+			return w.walk(val1, val2, mapIndexOf(original, key), pointer.Add(key.String()))
 		}
-		it = current.MapRange()
-		for it.Next() {
-			key := it.Key()
-			if key.Kind() != reflect.String {
-				return fmt.Errorf("only strings are supported as map keys but was: %s at: %s", key.Kind(), pointer)
-			}
 
+		if w.concurrent(modified.Len()) {
+			if modified.Type().Key().Kind() != reflect.String {
+				return fmt.Errorf("only strings are supported as map keys but was: %s at: %s", modified.Type().Key().Kind(), pointer)
+			}
+			// the pointer is clipped, otherwise the pointers of the children would share its array across goroutines
+			pointer = slices.Clip(pointer)
+			// the entries are walked in the order of their keys in order to merge the patches deterministically
+			keys := modified.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return keys[i].String() < keys[j].String()
+			})
+			if err := w.walkConcurrently(len(keys), func(w *walker, j int) error {
+				return walkEntry(w, keys[j], modified.MapIndex(keys[j]))
+			}); err != nil {
+				return err
+			}
+		} else {
+			it := modified.MapRange()
+			for it.Next() {
+				key := it.Key()
+				if key.Kind() != reflect.String {
+					return fmt.Errorf("only strings are supported as map keys but was: %s at: %s", key.Kind(), pointer)
+				}
+				if err := walkEntry(w, key, it.Value()); err != nil {
+					return err
+				}
+			}
+		}
+		removeEntry := func(key, val2 reflect.Value) error {
 			val1 := modified.MapIndex(key)
-			val2 := it.Value()
-			if val1.Kind() == reflect.Invalid {
-				w.remove(pointer.Add(key.String()), val2.Interface())
+			if val1.Kind() == reflect.Invalid && w.removable(mapIndexOf(original, key)) {
+				if err := w.ctx.Err(); err != nil {
+					return err
+				}
+				if ok, err := w.resolve(pointer.Add(key.String()), val1, val2, mapIndexOf(original, key)); err != nil {
+					return err
+				} else if ok {
+					w.remove(pointer.Add(key.String()), val2.Interface())
+				}
+			}
+
+			return nil
+		}
+
+		if w.concurrent(modified.Len()) || w.concurrent(current.Len()) {
+			if current.Type().Key().Kind() != reflect.String {
+				return fmt.Errorf("only strings are supported as map keys but was: %s at: %s", current.Type().Key().Kind(), pointer)
+			}
+			// the entries are removed in the order of their keys as well
+			keys := current.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return keys[i].String() < keys[j].String()
+			})
+			for _, key := range keys {
+				if err := removeEntry(key, current.MapIndex(key)); err != nil {
+					return err
+				}
+			}
+		} else {
+			it := current.MapRange()
+			for it.Next() {
+				key := it.Key()
+				if key.Kind() != reflect.String {
+					return fmt.Errorf("only strings are supported as map keys but was: %s at: %s", key.Kind(), pointer)
+				}
+				if err := removeEntry(key, it.Value()); err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -138,47 +578,35 @@ func (w *walker) processMap(modified reflect.Value, current reflect.Value, point
 }
 
 // processSlice processes reflect.Slice values
-func (w *walker) processSlice(modified reflect.Value, current reflect.Value, pointer JSONPointer) error {
-	if !w.predicate.Replace(pointer, modified.Interface(), current.Interface()) {
+func (w *walker) processSlice(modified, current, original reflect.Value, pointer JSONPointer) error {
+	if !descend(w.predicate, pointer, modified.Interface(), current.Interface()) {
 		return nil
 	}
 
 	if modified.Len() > 0 && current.Len() == 0 {
+		if ok, err := w.resolve(pointer, modified, current, original); !ok || err != nil {
+			return err
+		}
 		w.add(pointer, modified.Interface())
 	} else {
-		var ignoreSliceOrder bool
-		var patchSliceJSONField string
-
-		// look for a slice tag which pattern matches the pointer
-		for _, ignore := range w.ignoredSlices {
-			if pointer.Match(ignore.Pattern) {
-				ignoreSliceOrder = true
-				patchSliceJSONField = ignore.JSONField
-				break
+		if ignore, ok := w.ignoredSliceOf(pointer); ok {
+			if w.unordered != nil {
+				w.unordered(pointer, modified, current)
 			}
-		}
-
-		if ignoreSliceOrder {
-			fieldName := jsonFieldNameToFieldName(modified.Type().Elem(), patchSliceJSONField)
+			fieldIndex := jsonFieldNameToFieldIndex(modified.Type().Elem(), ignore.JSONField)
 
-			// maps the modified slice elements with the patchSliceKey to their index
-			idxMap1 := map[string]int{}
-			for j := 0; j < modified.Len(); j++ {
-				fieldValue := extractIgnoreSliceOrderMatchValue(modified.Index(j), fieldName)
-				if _, ok := idxMap1[fieldValue]; ok {
-					return fmt.Errorf("ignore slice order failed at %s due to unique match field constraint, duplicated value: %s", pointer, fieldValue)
-				}
-				idxMap1[fieldValue] = j
+			// maps the modified, current and original slice elements with the patchSliceKey to their index
+			idxMap1, err := indexSliceElements(modified, fieldIndex, pointer)
+			if err != nil {
+				return err
 			}
-
-			// maps the current slice elements with the patchSliceKey to their index
-			idxMap2 := map[string]int{}
-			for j := 0; j < current.Len(); j++ {
-				fieldValue := extractIgnoreSliceOrderMatchValue(current.Index(j), fieldName)
-				if _, ok := idxMap2[fieldValue]; ok {
-					return fmt.Errorf("ignore slice order failed at %s due to unique match field constraint, duplicated value: %s", pointer, fieldValue)
-				}
-				idxMap2[fieldValue] = j
+			idxMap2, err := indexSliceElements(current, fieldIndex, pointer)
+			if err != nil {
+				return err
+			}
+			idxMap3, err := indexSliceElements(original, fieldIndex, pointer)
+			if err != nil {
+				return err
 			}
 
 			// IMPORTANT: the order of the patches matters, because and add or delete will change the index of your
@@ -190,10 +618,18 @@ func (w *walker) processSlice(modified reflect.Value, current reflect.Value, poi
 			for k, idx1 := range idxMap1 {
 				idx2, ok := idxMap2[k]
 				if ok {
-					if err := w.walk(modified.Index(idx1), current.Index(idx2), pointer.Add(strconv.Itoa(idx2))); err != nil {
+					if err := w.walk(modified.Index(idx1), current.Index(idx2), indexOf(original, idxMap3, k), pointer.Add(strconv.Itoa(idx2))); err != nil {
 						return err
 					}
 				} else {
+					if err := w.ctx.Err(); err != nil {
+						return err
+					}
+					if ok, err := w.resolve(pointer.Add(strconv.Itoa(idxMax)), modified.Index(idx1), reflect.Value{}, indexOf(original, idxMap3, k)); err != nil {
+						return err
+					} else if !ok {
+						continue
+					}
 					if ok := w.add(pointer.Add(strconv.Itoa(idxMax)), modified.Index(idx1).Interface()); ok {
 						idxMax++
 					}
@@ -202,11 +638,19 @@ func (w *walker) processSlice(modified reflect.Value, current reflect.Value, poi
 
 			// IMPORTANT: deleting must be done in reverse order
 
-			// iterate through the list of current slice elements in order to identify deleted elements
+			// iterate through the list of current slice elements in order to identify deleted elements, in a
+			// three-way patch only the elements which were removed from the original slice are deleted
 			var deleted []int
 			for k, idx2 := range idxMap2 {
-				if _, ok := idxMap1[k]; !ok {
-					deleted = append(deleted, idx2)
+				if _, ok := idxMap1[k]; !ok && w.removable(indexOf(original, idxMap3, k)) {
+					if err := w.ctx.Err(); err != nil {
+						return err
+					}
+					if ok, err := w.resolve(pointer.Add(strconv.Itoa(idx2)), reflect.Value{}, current.Index(idx2), indexOf(original, idxMap3, k)); err != nil {
+						return err
+					} else if ok {
+						deleted = append(deleted, idx2)
+					}
 				}
 			}
 			sort.Ints(deleted)
@@ -214,17 +658,33 @@ func (w *walker) processSlice(modified reflect.Value, current reflect.Value, poi
 				idx2 := deleted[j]
 				w.remove(pointer.Add(strconv.Itoa(idx2)), current.Index(idx2).Interface())
 			}
+		} else if w.threeWay {
+			return w.processSliceThreeWay(modified, current, original, pointer)
 		} else {
 			// iterate through both slices and update their elements until on of them is completely processed
-			for j := 0; j < modified.Len() && j < current.Len(); j++ {
-				if err := w.walk(modified.Index(j), current.Index(j), pointer.Add(strconv.Itoa(j))); err != nil {
+			walkElement := func(w *walker, j int) error {
+				return w.walk(modified.Index(j), current.Index(j), reflect.Value{}, pointer.Add(strconv.Itoa(j)))
+			}
+			if n := min(modified.Len(), current.Len()); w.concurrent(n) {
+				// the pointer is clipped, otherwise the pointers of the children would share its array across goroutines
+				pointer = slices.Clip(pointer)
+				if err := w.walkConcurrently(n, walkElement); err != nil {
 					return err
 				}
+			} else {
+				for j := 0; j < n; j++ {
+					if err := walkElement(w, j); err != nil {
+						return err
+					}
+				}
 			}
 			if modified.Len() > current.Len() {
 				// add the remaining elements of the modified slice
 				idx := current.Len()
 				for j := current.Len(); j < modified.Len(); j++ {
+					if err := w.ctx.Err(); err != nil {
+						return err
+					}
 					if ok := w.add(pointer.Add(strconv.Itoa(idx)), modified.Index(j).Interface()); ok {
 						idx++
 					}
@@ -233,6 +693,9 @@ func (w *walker) processSlice(modified reflect.Value, current reflect.Value, poi
 				// delete the remaining elements of the current slice
 				// IMPORTANT: deleting must be done in reverse order
 				for j := current.Len() - 1; j >= modified.Len(); j-- {
+					if err := w.ctx.Err(); err != nil {
+						return err
+					}
 					w.remove(pointer.Add(strconv.Itoa(j)), current.Index(j).Interface())
 				}
 			}
@@ -242,16 +705,33 @@ func (w *walker) processSlice(modified reflect.Value, current reflect.Value, poi
 	return nil
 }
 
+// ignoredSliceOf returns the first IgnorePattern which pattern matches the pointer
+func (w *walker) ignoredSliceOf(pointer JSONPointer) (IgnorePattern, bool) {
+	for _, ignore := range w.ignoredSlices {
+		if pointer.Match(ignore.Pattern) {
+			return ignore, true
+		}
+	}
+
+	return IgnorePattern{}, false
+}
+
 // processPtr processes reflect.Ptr values
-func (w *walker) processPtr(modified reflect.Value, current reflect.Value, pointer JSONPointer) error {
+func (w *walker) processPtr(modified, current, original reflect.Value, pointer JSONPointer) error {
 	if !modified.IsNil() && !current.IsNil() {
 		// the values of the pointers will be processed in a next step
-		if err := w.walk(modified.Elem(), current.Elem(), pointer); err != nil {
+		if err := w.walk(modified.Elem(), current.Elem(), elemOf(original), pointer); err != nil {
 			return err
 		}
 	} else if !modified.IsNil() {
+		if ok, err := w.resolve(pointer, modified.Elem(), reflect.Value{}, elemOf(original)); !ok || err != nil {
+			return err
+		}
 		w.add(pointer, modified.Elem().Interface())
-	} else if !current.IsNil() {
+	} else if !current.IsNil() && w.removable(nonZero(original)) {
+		if ok, err := w.resolve(pointer, reflect.Value{}, current.Elem(), elemOf(original)); !ok || err != nil {
+			return err
+		}
 		w.remove(pointer, current.Elem().Interface())
 	}
 
@@ -259,8 +739,8 @@ func (w *walker) processPtr(modified reflect.Value, current reflect.Value, point
 }
 
 // processStruct processes reflect.Struct values
-func (w *walker) processStruct(modified, current reflect.Value, pointer JSONPointer) error {
-	if !w.predicate.Replace(pointer, modified.Interface(), current.Interface()) {
+func (w *walker) processStruct(modified, current, original reflect.Value, pointer JSONPointer) error {
+	if !descend(w.predicate, pointer, modified.Interface(), current.Interface()) {
 		return nil
 	}
 
@@ -274,18 +754,28 @@ func (w *walker) processStruct(modified, current reflect.Value, pointer JSONPoin
 		if err != nil {
 			return err
 		}
-		return w.processString(m, c, pointer)
+
+		var o reflect.Value
+		if original.IsValid() {
+			if o, err = toTimeStrValue(original); err != nil {
+				return err
+			}
+		}
+		return w.processString(m, c, o, pointer)
 	}
 
 	// process all struct fields, the order of the fields of the  modified and current JSON object is identical because their types match
-	for j := 0; j < modified.NumField(); j++ {
-		tag := strings.Split(modified.Type().Field(j).Tag.Get(jsonTag), ",")[0]
-		if tag == "" || tag == "_" || !modified.Field(j).CanInterface() {
-			// struct fields without a JSON tag set or unexported fields are ignored
+	for _, field := range structInfoOf(modified.Type()).fields {
+		fieldPointer := pointer.Add(field.name)
+		options, err := field.optionsAt(fieldPointer)
+		if err != nil {
+			return err
+		}
+		if options.ignore {
 			continue
 		}
 		// process the child's value of the modified and current JSON in a next step
-		if err := w.walk(modified.Field(j), current.Field(j), pointer.Add(tag)); err != nil {
+		if err := w.walkField(modified.Field(field.index), current.Field(field.index), fieldOf(original, field.index), fieldPointer, options); err != nil {
 			return err
 		}
 	}
@@ -293,6 +783,76 @@ func (w *walker) processStruct(modified, current reflect.Value, pointer JSONPoin
 	return nil
 }
 
+// walkField walks the value of a struct field, the options of the field are only applied to the value itself
+func (w *walker) walkField(modified, current, original reflect.Value, pointer JSONPointer, options fieldOptions) error {
+	if options.atomic {
+		w.atomic = append(w.atomic, pointer.String())
+		defer func() { w.atomic = w.atomic[:len(w.atomic)-1] }()
+	}
+	if options.unordered && (!options.merge || uniqueElements(options.key, pointer, modified, current, original)) {
+		// the pattern of the field takes precedence over all other patterns
+		ignoredSlices := w.ignoredSlices
+		w.ignoredSlices = append([]IgnorePattern{{Pattern: pointer.String(), JSONField: options.key}}, ignoredSlices...)
+		defer func() { w.ignoredSlices = ignoredSlices }()
+	}
+
+	return w.walk(modified, current, original, pointer)
+}
+
+// fieldOptions are the options of a struct field specified by its jsonpatch tag
+type fieldOptions struct {
+	atomic     bool
+	ignore     bool
+	unordered  bool
+	merge      bool
+	key        string
+	retainKeys bool
+	sensitive  bool
+}
+
+// parseFieldOptions parses the jsonpatch tag of a struct field, e.g. `jsonpatch:"unordered,key=name"`. Fields without
+// a jsonpatch tag fall back to the Kubernetes patchStrategy and patchMergeKey tags: slices with the 'merge' strategy are
+// unordered and matched by their merge key (unless their merge keys aren't unique), values with the 'replace' strategy
+// are atomic.
+func parseFieldOptions(tag reflect.StructTag, pointer JSONPointer) (fieldOptions, error) {
+	var options fieldOptions
+	value, ok := tag.Lookup(patchTag)
+	if !ok {
+		for _, strategy := range strings.Split(tag.Get(patchStrategyTag), ",") {
+			switch strategy {
+			case "merge":
+				options.unordered, options.merge = true, true
+				options.key = tag.Get(patchMergeKeyTag)
+			case "replace":
+				options.atomic = true
+			case "retainKeys":
+				options.retainKeys = true
+			}
+		}
+		return options, nil
+	}
+	for _, option := range strings.Split(value, ",") {
+		switch name, value, _ := strings.Cut(option, "="); name {
+		case "":
+			// an empty jsonpatch tag disables the Kubernetes tags
+		case "atomic":
+			options.atomic = true
+		case "ignore", "readonly":
+			options.ignore = true
+		case "unordered":
+			options.unordered = true
+		case "sensitive":
+			options.sensitive = true
+		case "key":
+			options.key = value
+		default:
+			return options, fmt.Errorf("unsupported jsonpatch tag option: %s at: %s", option, pointer)
+		}
+	}
+
+	return options, nil
+}
+
 func toTimeStrValue(v reflect.Value) (reflect.Value, error) {
 	t, err := v.Interface().(time.Time).MarshalText()
 	if err != nil {
@@ -301,14 +861,49 @@ func toTimeStrValue(v reflect.Value) (reflect.Value, error) {
 	return reflect.ValueOf(string(t)), nil
 }
 
+// indexSliceElements maps the slice elements with the value which is used to match them to their index
+func indexSliceElements(value reflect.Value, fieldIndex int, pointer JSONPointer) (map[string]int, error) {
+	idxMap := map[string]int{}
+	if !value.IsValid() {
+		return idxMap, nil
+	}
+	for j := 0; j < value.Len(); j++ {
+		fieldValue := extractIgnoreSliceOrderMatchValue(value.Index(j), fieldIndex)
+		if _, ok := idxMap[fieldValue]; ok {
+			return nil, fmt.Errorf("ignore slice order failed at %s due to unique match field constraint, duplicated value: %s", pointer, fieldValue)
+		}
+		idxMap[fieldValue] = j
+	}
+
+	return idxMap, nil
+}
+
+// uniqueElements reports whether the elements of the slices are unique by the value of their JSON field, invalid values
+// and values which aren't slices are ignored
+func uniqueElements(jsonField string, pointer JSONPointer, values ...reflect.Value) bool {
+	for _, value := range values {
+		if !value.IsValid() || value.Kind() != reflect.Slice {
+			continue
+		}
+		if _, err := indexSliceElements(value, jsonFieldNameToFieldIndex(value.Type().Elem(), jsonField), pointer); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
 // extractIgnoreSliceOrderMatchValue extracts the value which is used to match the modified and current values to ignore the slice order
-func extractIgnoreSliceOrderMatchValue(value reflect.Value, fieldName string) string {
+func extractIgnoreSliceOrderMatchValue(value reflect.Value, fieldIndex int) string {
 	switch value.Kind() {
 	case reflect.Struct:
-		return extractIgnoreSliceOrderMatchValue(value.FieldByName(fieldName), "")
+		if fieldIndex < 0 {
+			return ""
+		}
+		return extractIgnoreSliceOrderMatchValue(value.Field(fieldIndex), -1)
 	case reflect.Pointer:
 		if !value.IsNil() {
-			return extractIgnoreSliceOrderMatchValue(value.Elem(), fieldName)
+			return extractIgnoreSliceOrderMatchValue(value.Elem(), fieldIndex)
 		}
 		return ""
 	case reflect.String:
@@ -327,48 +922,192 @@ func extractIgnoreSliceOrderMatchValue(value reflect.Value, fieldName string) st
 	return ""
 }
 
-// jsonFieldNameToFieldName retrieves the actual Go field name for the JSON field name
-func jsonFieldNameToFieldName(t reflect.Type, jsonFieldName string) string {
+// jsonFieldNameToFieldIndex retrieves the index of the actual Go field for the JSON field name or -1 if there is none
+func jsonFieldNameToFieldIndex(t reflect.Type, jsonFieldName string) int {
 	switch t.Kind() {
 	case reflect.Struct:
-		for i := 0; i < t.NumField(); i++ {
-			if strings.Split(t.Field(i).Tag.Get(jsonTag), ",")[0] == jsonFieldName {
-				return t.Field(i).Name
-			}
+		if j, ok := structInfoOf(t).indices[jsonFieldName]; ok {
+			return j
 		}
 	case reflect.Pointer:
-		return jsonFieldNameToFieldName(t.Elem(), jsonFieldName)
+		return jsonFieldNameToFieldIndex(t.Elem(), jsonFieldName)
 	}
 
-	return ""
+	return -1
+}
+
+// resolve checks in a three-way patch whether the change of a value in the modified JSON conflicts with a change of
+// the value in the current JSON and resolves the conflict using the ConflictResolver. It returns false if the value
+// must not be patched. Invalid values are not part of the respective JSON.
+func (w *walker) resolve(pointer JSONPointer, modified, current, original reflect.Value) (bool, error) {
+	if !w.threeWay {
+		return true, nil
+	}
+
+	return w.resolveKeys(pointer, modified, current, original, nil, nil, nil)
+}
+
+// resolveKeys resolves a conflict like resolve, the keys of the values are computed once for all comparisons unless
+// they are already known
+func (w *walker) resolveKeys(pointer JSONPointer, modified, current, original reflect.Value, modifiedKey, currentKey, originalKey *valueKey) (bool, error) {
+	key := func(v reflect.Value, k *valueKey) *valueKey {
+		if k == nil {
+			key := w.keyOf(v)
+			k = &key
+		}
+		return k
+	}
+	originalKey, currentKey = key(original, originalKey), key(current, currentKey)
+	if !currentKey.differs(*originalKey) {
+		return true, nil
+	}
+	modifiedKey = key(modified, modifiedKey)
+	if !modifiedKey.differs(*originalKey) || !modifiedKey.differs(*currentKey) {
+		return true, nil
+	}
+
+	conflict := Conflict{
+		Pointer:  pointer,
+		Original: interfaceOf(original),
+		Current:  interfaceOf(current),
+		Modified: interfaceOf(modified),
+	}
+	w.conflicts = append(w.conflicts, conflict)
+
+	return w.resolver(conflict)
+}
+
+// changed reports whether the value a differs from the value b in terms of their JSON representation
+func (w *walker) changed(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() != b.IsValid()
+	}
+
+	return w.keyOf(a).differs(w.keyOf(b))
+}
+
+// removable reports whether a value which is missing in the modified JSON is removed from the current JSON. In a
+// three-way patch only values which are part of the original JSON are removed, all others were added by someone else.
+func (w *walker) removable(original reflect.Value) bool {
+	return !w.threeWay || original.IsValid()
+}
+
+// nonZero returns the value or an invalid value if it is the zero value of its type (e.g. empty strings or nil pointers)
+func nonZero(v reflect.Value) reflect.Value {
+	if !v.IsValid() || v.IsZero() {
+		return reflect.Value{}
+	}
+
+	return v
+}
+
+// orZero returns the value or the zero value of the type if the value is invalid
+func orZero(v reflect.Value, t reflect.Type) reflect.Value {
+	if !v.IsValid() {
+		return reflect.Zero(t)
+	}
+
+	return v
+}
+
+// interfaceOf returns the value as interface{} or nil if the value is invalid
+func interfaceOf(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	return v.Interface()
+}
+
+// elemOf returns the value of a pointer or interface or an invalid value if there is none
+func elemOf(v reflect.Value) reflect.Value {
+	if !v.IsValid() || v.IsNil() {
+		return reflect.Value{}
+	}
+	if v.Kind() == reflect.Interface {
+		return reflect.ValueOf(v.Interface())
+	}
+
+	return v.Elem()
+}
+
+// fieldOf returns the j-th field of a struct or an invalid value if there is none
+func fieldOf(v reflect.Value, j int) reflect.Value {
+	if !v.IsValid() {
+		return reflect.Value{}
+	}
+
+	return v.Field(j)
+}
+
+// mapIndexOf returns the value of a map for the key or an invalid value if there is none
+func mapIndexOf(v reflect.Value, key reflect.Value) reflect.Value {
+	if !v.IsValid() {
+		return reflect.Value{}
+	}
+
+	return v.MapIndex(key)
+}
+
+// indexOf returns the element of a slice which is mapped to the key or an invalid value if there is none
+func indexOf[K comparable](v reflect.Value, idxMap map[K]int, key K) reflect.Value {
+	if idx, ok := idxMap[key]; ok {
+		return v.Index(idx)
+	}
+
+	return reflect.Value{}
 }
 
 // add adds an add JSON patch by checking the Predicate first and using the Handler to generate it
 func (w *walker) add(pointer JSONPointer, modified interface{}) bool {
-	if w.predicate != nil && !w.predicate.Add(pointer, modified) {
+	// the leaves of a walk which is aborted are neither filtered nor patched
+	if w.aborted() {
+		return false
+	}
+	if w.predicate != nil && !w.predicate.Add(pointer, modified) || !w.decide(pointer, "add", modified, nil) {
+		return false
+	}
+	if w.violates(pointer, modified, nil) {
 		return false
 	}
 	w.patchList = append(w.patchList, w.handler.Add(pointer, modified)...)
+	w.flush()
 
 	return true
 }
 
 // replace adds a replace JSON patch by checking the Predicate first and using the Handler to generate it
 func (w *walker) replace(pointer JSONPointer, modified, current interface{}) bool {
-	if w.predicate != nil && !w.predicate.Replace(pointer, modified, current) {
+	// the leaves of a walk which is aborted are neither filtered nor patched
+	if w.aborted() {
+		return false
+	}
+	if w.predicate != nil && !w.predicate.Replace(pointer, modified, current) || !w.decide(pointer, "replace", modified, current) {
+		return false
+	}
+	if w.violates(pointer, modified, current) {
 		return false
 	}
 	w.patchList = append(w.patchList, w.handler.Replace(pointer, modified, current)...)
+	w.flush()
 
 	return true
 }
 
 // remove adds a remove JSON patch by checking the Predicate first and using the Handler to generate it
 func (w *walker) remove(pointer JSONPointer, current interface{}) bool {
-	if w.predicate != nil && !w.predicate.Remove(pointer, current) {
+	// the leaves of a walk which is aborted are neither filtered nor patched
+	if w.aborted() {
+		return false
+	}
+	if w.predicate != nil && !w.predicate.Remove(pointer, current) || !w.decide(pointer, "remove", nil, current) {
+		return false
+	}
+	if w.violates(pointer, nil, current) {
 		return false
 	}
 	w.patchList = append(w.patchList, w.handler.Remove(pointer, current)...)
+	w.flush()
 
 	return true
 }